@@ -3,16 +3,22 @@ package main
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"time"
 
 	"github.com/llywelwyn/wow/internal/command"
 	"github.com/llywelwyn/wow/internal/config"
 	"github.com/llywelwyn/wow/internal/editor"
+	"github.com/llywelwyn/wow/internal/hooks"
 	"github.com/llywelwyn/wow/internal/opener"
 	"github.com/llywelwyn/wow/internal/pager"
 	"github.com/llywelwyn/wow/internal/runner"
-	"github.com/llywelwyn/wow/internal/storage"
+	"github.com/llywelwyn/wow/internal/services"
+	"github.com/llywelwyn/wow/internal/vault"
 )
 
 func main() {
@@ -23,46 +29,138 @@ func main() {
 }
 
 func run() error {
+	// os.Args[0] is this script. Take the rest.
+	args, global, err := extractGlobalFlags(os.Args[1:])
+	if err != nil {
+		return err
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
+	if cfg, err = cfg.WithScope(global.scope); err != nil {
+		return err
+	}
 
-	db, err := storage.InitMetaDB(cfg.MetaDB)
+	vaults, err := vault.Open(cfg.Vaults)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
+	defer vaults.Close()
+	db := vaults.Primary().DB
 
 	dispatcher := command.NewDispatcher()
+	dispatcher.PluginDirs = append(filepath.SplitList(os.Getenv("PATH")), filepath.Join(cfg.UserDir(), "plugins"))
+	dispatcher.PluginEnv = []string{
+		"WOW_HOME=" + cfg.UserDir(),
+		"WOW_DB=" + cfg.MetaDB,
+		"WOW_VAULT=" + vaults.Primary().Name,
+	}
+
+	lines := maps.Clone(runner.DefaultLineTemplates)
+	maps.Copy(lines, cfg.Line)
 
 	cmdCfg := command.Config{
+		Home:    cfg.UserDir(),
 		BaseDir: cfg.BaseDir,
 		DB:      db,
 		Input:   os.Stdin,
 		Output:  os.Stdout,
 		Clock:   time.Now,
-		Editor:  runner.Run(editor.GetEditorFromEnv()),
-		Opener:  runner.Run(opener.GetOpenerFromEnv()),
-		Pager:   runner.Run(pager.GetPagerFromEnv()),
+		Editor:  runner.Run(editor.GetEditorFromEnv(), lines),
+		Opener:  runner.Run(opener.GetOpenerFromEnv(), lines),
+		Pager:   runner.Run(pager.GetPagerFromEnv(), lines),
+		System:  runner.Run(opener.SystemOpener(), lines),
+		Vaults:  vaults,
+
+		MaxSnippetSize: cfg.MaxSnippetSize,
+		CompressAbove:  cfg.CompressAbove,
+		Redact:         cfg.Redact,
+	}
+	policy, err := services.ParseSecretPolicy(cfg.SecretPolicy)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	cmdCfg.Secrets = &services.SecretScanner{Rules: slices.Clone(services.DefaultSecretRules), Policy: policy}
+	for _, rule := range cfg.SecretRules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("config: secrets rule %q: %w", rule.Name, err)
+		}
+		cmdCfg.Secrets.Rules = append(cmdCfg.Secrets.Rules, services.SecretRule{Name: rule.Name, Pattern: pattern})
+	}
+	for _, rule := range cfg.Open {
+		open, err := runner.Command(rule.Command, lines)
+		if err != nil {
+			return fmt.Errorf("config: open rule %q: %w", rule.Name, err)
+		}
+		cmdCfg.OpenRules = append(cmdCfg.OpenRules, services.OpenRule{
+			Name:    rule.Name,
+			Keys:    rule.Keys,
+			Type:    rule.Type,
+			Lang:    rule.Lang,
+			MIME:    rule.MIME,
+			Command: rule.Command,
+			Open:    open,
+		})
+	}
+	pageOutput := runner.Pipe(pager.GetPagerFromEnv(), pager.Env())
+	if cfg.Paging("get") {
+		cmdCfg.PageOutput = pageOutput
+	}
+	if cfg.Paging("view") {
+		cmdCfg.PageView = pageOutput
+	}
+	if !global.noHooks {
+		cmdCfg.Hooks = &hooks.Runner{Dir: filepath.Join(cfg.UserDir(), "hooks")}
 	}
 
 	saveCmd := command.NewSaveCommand(cmdCfg)
+	newCmd := command.NewNewCommand(cmdCfg)
 	getCmd := command.NewGetCommand(cmdCfg)
+	viewCmd := command.NewViewCommand(cmdCfg)
 	editCmd := command.NewEditCommand(cmdCfg)
 	openCmd := command.NewOpenCommand(cmdCfg)
 	listCmd := command.NewListCommand(cmdCfg)
 	removeCmd := command.NewRemoveCommand(cmdCfg)
+	setCmd := command.NewSetCommand(cmdCfg)
+	compactCmd := command.NewCompactCommand(cmdCfg)
+	dupesCmd := command.NewDupesCommand(cmdCfg)
+	verifyCmd := command.NewVerifyCommand(cmdCfg)
+	encryptCmd := command.NewEncryptCommand(cmdCfg)
+	decryptCmd := command.NewDecryptCommand(cmdCfg)
+	scanCmd := command.NewScanCommand(cmdCfg)
+	protectCmd := command.NewProtectCommand(cmdCfg)
+	unprotectCmd := command.NewUnprotectCommand(cmdCfg)
+	vaultsCmd := command.NewVaultsCommand(cmdCfg)
+	initCmd := command.NewInitCommand(cmdCfg)
 
 	dispatcher.Register(saveCmd)
+	dispatcher.Register(newCmd)
 	dispatcher.Register(getCmd)
+	dispatcher.Register(viewCmd)
 	dispatcher.Register(editCmd)
 	dispatcher.Register(openCmd)
 	dispatcher.Register(listCmd, "ls")
 	dispatcher.Register(removeCmd, "rm")
+	dispatcher.Register(setCmd)
+	dispatcher.Register(compactCmd)
+	dispatcher.Register(dupesCmd)
+	dispatcher.Register(verifyCmd)
+	dispatcher.Register(encryptCmd)
+	dispatcher.Register(decryptCmd)
+	dispatcher.Register(scanCmd)
+	dispatcher.Register(protectCmd)
+	dispatcher.Register(unprotectCmd)
+	dispatcher.Register(vaultsCmd)
+	dispatcher.Register(initCmd)
+	dispatcher.Register(&helpCommand{dispatcher: dispatcher})
 
-	// os.Args[0] is this script. Take the rest.
-	args := os.Args[1:]
+	if err := dispatcher.RegisterAliases(cfg.Aliases); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	args = dispatcher.Expand(args)
 
 	piped, err := stdinHasData()
 	if err != nil {
@@ -77,12 +175,12 @@ func run() error {
 			// Implicit save with auto-generated key.
 			return saveCmd.Execute(nil)
 		}
-		printUsage()
+		printUsage(dispatcher.Plugins())
 		return nil
 	}
 
 	if args[0] == "--help" || args[0] == "-h" {
-		printUsage()
+		printUsage(dispatcher.Plugins())
 		return nil
 	}
 
@@ -94,6 +192,15 @@ func run() error {
 		return cmd.Execute(args[1:])
 	}
 
+	// Then for a plugin, unless args[0] is a key
+	// an implicit get would find. Keys win.
+	if _, _, err := vaults.Resolve(args[0]); err != nil {
+		if plugin, ok := dispatcher.LookupPlugin(args[0]); ok {
+			// wow deploy --env prod  -->  wow-deploy --env prod
+			return plugin.Execute(args[1:])
+		}
+	}
+
 	// Implicit save or get based on stdin presence.
 	if piped {
 		// echo "func foo() {}" | wow go/foo
@@ -103,6 +210,38 @@ func run() error {
 	return getCmd.Execute(args)
 }
 
+// globalFlags apply to every command.
+type globalFlags struct {
+	scope   config.Scope // which vault receives writes.
+	noHooks bool         // skip lifecycle hooks.
+}
+
+// extractGlobalFlags strips the global flags that come before the
+// command word. Everything from the command word or a "--" on is left
+// alone, so plugins and aliases receive their arguments as given.
+func extractGlobalFlags(args []string) ([]string, globalFlags, error) {
+	var flags globalFlags
+	for i, arg := range args {
+		var want config.Scope
+		switch arg {
+		case "--global":
+			want = config.ScopeGlobal
+		case "--local":
+			want = config.ScopeLocal
+		case "--no-hooks":
+			flags.noHooks = true
+			continue
+		default:
+			return args[i:], flags, nil
+		}
+		if flags.scope != config.ScopeDefault && flags.scope != want {
+			return nil, flags, errors.New("--global and --local are mutually exclusive")
+		}
+		flags.scope = want
+	}
+	return nil, flags, nil
+}
+
 func stdinHasData() (bool, error) {
 	info, err := os.Stdin.Stat()
 	if err != nil {
@@ -114,16 +253,49 @@ func stdinHasData() (bool, error) {
 	return info.Mode()&os.ModeCharDevice == 0, nil
 }
 
-func printUsage() {
+// helpCommand prints usage, or the help of another command.
+type helpCommand struct {
+	dispatcher *command.Dispatcher
+}
+
+func (h *helpCommand) Name() string { return "help" }
+
+func (h *helpCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		printUsage(h.dispatcher.Plugins())
+		return nil
+	}
+	if cmd, ok := h.dispatcher.Lookup(args[0]); ok {
+		return cmd.Execute([]string{"--help"})
+	}
+	if plugin, ok := h.dispatcher.LookupPlugin(args[0]); ok {
+		return plugin.Execute([]string{"--help"})
+	}
+	return fmt.Errorf("%w: %s", command.ErrUnknownCommand, args[0])
+}
+
+func printUsage(plugins []string) {
 
 	fmt.Fprintf(os.Stdout, `Usage:
   wow get    <key> [--tag str] [--untag str] [@tag] [-@tag]  Get a snippet.
   wow save   <key> [--tag str] [--desc str] [@tag]           Save a snippet.
-  wow open   <key> [--pager]                                 Open a snippet. 
-  wow edit   <key>                                           Edit a snippet.
+  wow new    [key] [--from key]                              Write a snippet in your editor.
+  wow view   <key> [--raw]                                   Render a Markdown snippet.
+  wow open   <key>[:line] [--pager] [--with rule]            Open a snippet.
+  wow edit   <key>[:line] [--meta] [--all]                   Edit a snippet.
   wow remove <key>                                           Remove a snippet.
-  wow list [--limit int] [--page int] [--plain] [--verbose]  List snippets. 
-           [--tags] [--type] [--desc] [--dates] [--all]
+  wow list [--limit int] [--page int] [--plain] [--verbose]  List snippets.
+  wow set    <key> [--type str] [--lang str]                 Override a snippet's type.
+  wow compact [--above size] [--dry-run]                     Compress large snippets.
+  wow dupes  [--merge]                                       Find snippets saved twice.
+  wow verify [prefix] [--accept]                             Check files against their hashes.
+  wow encrypt <key>...                                       Encrypt snippets with a passphrase.
+  wow decrypt <key>...                                       Store snippets as plain text again.
+  wow scan   [prefix]                                        Look for secrets in saved snippets.
+  wow protect <key|prefix>...                                Guard snippets from changes.
+  wow unprotect <key|prefix>...                              Lift that protection.
+  wow vaults [--plain]                                       Show vault layering.
+  wow init   [dir]                                           Create a project vault.
   wow help [command]                                         Get specific help.
   
   Run any command with --help for more info.
@@ -136,5 +308,22 @@ func printUsage() {
   just write your flags separately.
 
   For example: "wow list -tdl 2" is --tags, --desc, and --limit 2.
+
+  Put --global or --local before any command to pick the user or
+  project vault, or --no-hooks to skip hooks. Aliases are set under
+  [alias] in config.toml, e.g. urls = "list --type --verbose", and
+  run with the shell when they start with "!".
 `)
+
+	if len(plugins) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stdout, `
+Plugins:
+  Found as wow-<name> on $PATH or in $WOW_HOME/plugins.
+
+`)
+	for _, name := range plugins {
+		fmt.Fprintf(os.Stdout, "  wow %s\n", name)
+	}
 }
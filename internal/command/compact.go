@@ -0,0 +1,120 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/config"
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+// CompactCommand compresses large snippets already in the vault.
+type CompactCommand struct {
+	Compactor *services.Compactor
+	Output    io.Writer
+}
+
+// NewCompactCommand constructs a CompactCommand using defaults from cfg.
+func NewCompactCommand(cfg Config) *CompactCommand {
+	var compactor *services.Compactor
+	if cfg.DB != nil {
+		compactor = &services.Compactor{
+			BaseDir:       cfg.BaseDir,
+			DB:            cfg.DB,
+			CompressAbove: cfg.CompressAbove,
+		}
+	}
+	return &CompactCommand{
+		Compactor: compactor,
+		Output:    cfg.writer(),
+	}
+}
+
+// Name returns the command keyword.
+func (c *CompactCommand) Name() string { return "compact" }
+
+// Execute compresses every snippet over the threshold and reports the space saved.
+func (c *CompactCommand) Execute(args []string) error {
+	if c.Output == nil || c.Compactor == nil {
+		return errors.New("compact command not fully configured")
+	}
+
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var dryRun *bool = fs.BoolP("dry-run", "n", false, "report what would be saved without writing anything")
+	var above *string = fs.StringP("above", "a", "", "compress snippets of at least this size, e.g. 64KB")
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow compact [--above size] [--dry-run]
+
+  wow! Compresses snippets stored before they were
+  over compress_above in config.toml, and reports
+  the space saved. Snippets that would not shrink,
+  such as images, are left as they are.
+
+  Compressed snippets read as usual everywhere.
+
+  Some examples:
+    wow compact --dry-run
+    wow compact --above 1MB`)
+		fmt.Fprintln(c.Output)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	if len(fs.Args()) > 0 {
+		return errors.New("compact takes no arguments")
+	}
+
+	svc := *c.Compactor
+	if *above != "" {
+		size, err := config.ParseByteSize(*above)
+		if err != nil {
+			return fmt.Errorf("--above: %w", err)
+		}
+		svc.CompressAbove = size
+	}
+	if svc.CompressAbove <= 0 {
+		return errors.New("no size to compress above; set compress_above in config.toml or pass --above")
+	}
+
+	results, err := svc.Compact(context.Background(), *dryRun)
+	for _, res := range results {
+		fmt.Fprintf(c.Output, "%s %s -> %s\n", res.Key, formatSize(res.Before), formatSize(res.After))
+	}
+	if err != nil {
+		return err
+	}
+	return writeCompactSummary(c.Output, results, *dryRun)
+}
+
+func writeCompactSummary(w io.Writer, results []services.CompactResult, dryRun bool) error {
+	if len(results) == 0 {
+		_, err := fmt.Fprintln(w, "nothing to compact")
+		return err
+	}
+	var saved int64
+	for _, res := range results {
+		saved += res.Before - res.After
+	}
+	noun := "snippets"
+	if len(results) == 1 {
+		noun = "snippet"
+	}
+	verb := "saved"
+	if dryRun {
+		verb = "would save"
+	}
+	_, err := fmt.Fprintf(w, "%s %s by compressing %d %s\n", verb, formatSize(saved), len(results), noun)
+	return err
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/services"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func TestCompactCommandReportsSavings(t *testing.T) {
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+
+	content := strings.Repeat("DEBUG cache miss for user 42\n", 400)
+	if _, err := saver.Save(context.Background(), services.SaveRequest{Key: "logs/cache", Reader: strings.NewReader(content)}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	cmd := NewCompactCommand(cfg)
+
+	if err := cmd.Execute(nil); err == nil || !strings.Contains(err.Error(), "compress_above") {
+		t.Fatalf("Execute without a threshold error = %v, want a hint at compress_above", err)
+	}
+
+	if err := cmd.Execute([]string{"--above", "1KB"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "logs/cache 11.3 KB -> ") {
+		t.Fatalf("output = %q, want the snippet then a summary", out.String())
+	}
+	if !strings.HasPrefix(lines[1], "saved ") || !strings.HasSuffix(lines[1], " by compressing 1 snippet") {
+		t.Fatalf("summary = %q", lines[1])
+	}
+
+	meta, err := storage.GetMetadata(context.Background(), cfg.DB, "logs/cache")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if meta.Encoding != storage.EncodingGzip {
+		t.Fatalf("Encoding = %q, want gzip", meta.Encoding)
+	}
+
+	out.Reset()
+	if err := cmd.Execute([]string{"--above", "1KB"}); err != nil {
+		t.Fatalf("second Execute error = %v", err)
+	}
+	if out.String() != "nothing to compact\n" {
+		t.Fatalf("second output = %q", out.String())
+	}
+}
@@ -7,18 +7,37 @@ import (
 	"io"
 	"os"
 	"time"
+
+	"github.com/llywelwyn/wow/internal/hooks"
+	"github.com/llywelwyn/wow/internal/services"
+	"github.com/llywelwyn/wow/internal/storage"
+	"github.com/llywelwyn/wow/internal/vault"
 )
 
 // Config captures the common environment used to construct default commands.
 type Config struct {
-	BaseDir string
-	DB      *sql.DB
-	Input   io.Reader
-	Output  io.Writer
-	Clock   func() time.Time
-	Editor  func(context.Context, string) error
-	Opener  func(context.Context, string) error
-	Pager   func(context.Context, string) error
+	Home       string // user vault directory, even when writes go elsewhere.
+	BaseDir    string
+	DB         *sql.DB
+	Input      io.Reader
+	Output     io.Writer
+	Clock      func() time.Time
+	Editor     func(context.Context, string) error
+	Opener     func(context.Context, string) error
+	Pager      func(context.Context, string) error
+	System     func(context.Context, string) error    // opens a file with the program the system has for its media type.
+	Vaults     vault.Stack                            // read search path; defaults to a single vault at BaseDir.
+	Hooks      *hooks.Runner                          // nil disables lifecycle hooks.
+	OpenRules  []services.OpenRule                    // tried by open before Opener.
+	PageOutput func(context.Context, io.Reader) error // pages long get output; nil disables.
+	PageView   func(context.Context, io.Reader) error // pages long view output; nil disables.
+	Status     io.Writer                              // where progress is shown; defaults to stderr when it is a terminal.
+	Passphrase storage.Passphrase                     // for encrypted snippets; defaults to $WOW_PASSPHRASE, or asking on the terminal.
+	Secrets    *services.SecretScanner                // scans saves and edits for secrets; nil skips scanning.
+	Redact     bool                                   // masks secrets in get, view and list on a terminal unless --redact=false.
+
+	MaxSnippetSize int64 // largest snippet saved, in bytes; zero for no limit.
+	CompressAbove  int64 // size from which snippets are stored gzipped; zero never compresses.
 }
 
 func (c Config) reader() io.Reader {
@@ -35,6 +54,16 @@ func (c Config) writer() io.Writer {
 	return os.Stdout
 }
 
+func (c Config) status() io.Writer {
+	if c.Status != nil {
+		return c.Status
+	}
+	if writerIsTerminal(os.Stderr) {
+		return os.Stderr
+	}
+	return nil
+}
+
 func (c Config) clock() func() time.Time {
 	if c.Clock != nil {
 		return c.Clock
@@ -42,6 +71,13 @@ func (c Config) clock() func() time.Time {
 	return time.Now
 }
 
+func (c Config) vaults() vault.Stack {
+	if len(c.Vaults) > 0 {
+		return c.Vaults
+	}
+	return vault.Stack{{Name: "user", BaseDir: c.BaseDir, DB: c.DB}}
+}
+
 func (c Config) editor() func(context.Context, string) error {
 	if c.Editor != nil {
 		return c.Editor
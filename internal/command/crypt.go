@@ -0,0 +1,134 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+// EncryptCommand seals existing snippets with a passphrase.
+type EncryptCommand struct {
+	Crypter *services.Crypter
+	Output  io.Writer
+}
+
+// NewEncryptCommand constructs an EncryptCommand using defaults from cfg.
+func NewEncryptCommand(cfg Config) *EncryptCommand {
+	return &EncryptCommand{
+		Crypter: newCrypter(cfg, true),
+		Output:  cfg.writer(),
+	}
+}
+
+// Name returns the command keyword.
+func (c *EncryptCommand) Name() string { return "encrypt" }
+
+// Execute encrypts each snippet named in args.
+func (c *EncryptCommand) Execute(args []string) error {
+	if c.Output == nil || c.Crypter == nil {
+		return errors.New("encrypt command not fully configured")
+	}
+	return runCrypt(c.Output, "encrypt", args, c.Crypter, (*services.Crypter).Encrypt, `Usage:
+  wow encrypt <key> [key ...] [--force]
+
+  wow! Encrypts snippets already saved, so their
+  files hold only AES-GCM ciphertext. The passphrase is
+  taken from $WOW_PASSPHRASE, or asked for twice.
+
+  Encrypted snippets are decrypted when you get,
+  view, edit or open them; plain copies made for
+  your editor or opener are overwritten and removed
+  afterwards. Run "wow decrypt" to store one as
+  plain text again.
+
+  Protected snippets are only encrypted with --force.`)
+}
+
+// DecryptCommand stores encrypted snippets as plain text again.
+type DecryptCommand struct {
+	Crypter *services.Crypter
+	Output  io.Writer
+}
+
+// NewDecryptCommand constructs a DecryptCommand using defaults from cfg.
+func NewDecryptCommand(cfg Config) *DecryptCommand {
+	return &DecryptCommand{
+		Crypter: newCrypter(cfg, false),
+		Output:  cfg.writer(),
+	}
+}
+
+// Name returns the command keyword.
+func (c *DecryptCommand) Name() string { return "decrypt" }
+
+// Execute decrypts each snippet named in args.
+func (c *DecryptCommand) Execute(args []string) error {
+	if c.Output == nil || c.Crypter == nil {
+		return errors.New("decrypt command not fully configured")
+	}
+	return runCrypt(c.Output, "decrypt", args, c.Crypter, (*services.Crypter).Decrypt, `Usage:
+  wow decrypt <key> [key ...] [--force]
+
+  wow! Stores encrypted snippets as plain text
+  again. The passphrase is taken from
+  $WOW_PASSPHRASE, or asked for.
+
+  Protected snippets are only decrypted with --force.`)
+}
+
+func newCrypter(cfg Config, confirm bool) *services.Crypter {
+	if cfg.DB == nil {
+		return nil
+	}
+	return &services.Crypter{
+		BaseDir:       cfg.BaseDir,
+		DB:            cfg.DB,
+		Passphrase:    cfg.passphrase(confirm),
+		CompressAbove: cfg.CompressAbove,
+	}
+}
+
+// runCrypt parses the flags shared by encrypt and decrypt, then applies
+// rewrite with crypter to each key in turn, stopping at the first that fails.
+func runCrypt(out io.Writer, name string, args []string, crypter *services.Crypter, rewrite func(*services.Crypter, context.Context, string) (model.Metadata, error), usage string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	var force *bool = fs.BoolP("force", "f", false, name+" protected snippets too")
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(out, usage)
+		fmt.Fprintln(out)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	keys := fs.Args()
+	if len(keys) == 0 {
+		return fmt.Errorf("%s needs at least one key", name)
+	}
+
+	svc := *crypter
+	svc.Force = *force
+	ctx := context.Background()
+	for _, k := range keys {
+		meta, err := rewrite(&svc, ctx, k)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "%sed %s\n", name, meta.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,16 +1,28 @@
 package command
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/llywelwyn/wow/internal/runner"
+)
 
 // Dispatcher routes CLI args to registered commands.
 type Dispatcher struct {
 	registry map[string]Command
+	aliases  map[string][]string // user aliases expanding to other commands.
+
+	PluginDirs []string // searched in order by LookupPlugin.
+	PluginEnv  []string // extra environment passed to plugins.
 }
 
 // NewDispatcher constructs an empty Dispatcher.
 func NewDispatcher() *Dispatcher {
 	return &Dispatcher{
 		registry: make(map[string]Command),
+		aliases:  make(map[string][]string),
 	}
 }
 
@@ -43,8 +55,106 @@ func (d *Dispatcher) addRoute(name string, cmd Command) {
 	d.registry[name] = cmd
 }
 
+// RegisterAliases adds user-defined aliases, such as those from config.
+//
+// Each expansion is a command line the alias stands for, split like a
+// shell would, and any arguments after the alias are passed through
+// after it. Expansions starting with "!" are run with sh instead.
+//
+// Aliases must be registered after every command. It errors if an alias
+// shadows a command or if aliases expand into each other in a loop.
+func (d *Dispatcher) RegisterAliases(aliases map[string]string) error {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	expansions := make(map[string][]string, len(aliases))
+	shells := make(map[string]string)
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, " \t") || strings.HasPrefix(name, "-") {
+			return fmt.Errorf("alias %q: invalid name", name)
+		}
+		if _, exists := d.registry[name]; exists {
+			return fmt.Errorf("alias %q: shadows a built-in command", name)
+		}
+		if _, exists := d.aliases[name]; exists {
+			return fmt.Errorf("alias %q: already registered", name)
+		}
+
+		expansion := strings.TrimSpace(aliases[name])
+		if script, ok := strings.CutPrefix(expansion, "!"); ok {
+			if strings.TrimSpace(script) == "" {
+				return fmt.Errorf("alias %q: empty shell command", name)
+			}
+			shells[name] = script
+			continue
+		}
+
+		fields, err := runner.Split(expansion)
+		if err != nil {
+			return fmt.Errorf("alias %q: %w", name, err)
+		}
+		if len(fields) == 0 {
+			return fmt.Errorf("alias %q: empty expansion", name)
+		}
+		expansions[name] = fields
+	}
+
+	for _, name := range names {
+		if _, ok := expansions[name]; !ok {
+			continue
+		}
+		if err := checkAliasCycle(name, expansions); err != nil {
+			return err
+		}
+	}
+
+	for name, fields := range expansions {
+		d.aliases[name] = fields
+	}
+	for name, script := range shells {
+		d.addRoute(name, &shellAlias{name: name, script: script})
+	}
+	return nil
+}
+
+// checkAliasCycle follows the chain of aliases starting at name
+// and errors if it ever comes back round.
+func checkAliasCycle(name string, expansions map[string][]string) error {
+	chain := []string{name}
+	seen := map[string]bool{name: true}
+	for current := name; ; {
+		next := expansions[current][0]
+		if _, isAlias := expansions[next]; !isAlias {
+			return nil
+		}
+		chain = append(chain, next)
+		if seen[next] {
+			return fmt.Errorf("alias %q: cycle %s", name, strings.Join(chain, " -> "))
+		}
+		seen[next] = true
+		current = next
+	}
+}
+
+// Expand rewrites args while the first one names an alias.
+// Arguments following the alias are kept after its expansion.
+func (d *Dispatcher) Expand(args []string) []string {
+	for len(args) > 0 {
+		fields, ok := d.aliases[args[0]]
+		if !ok {
+			return args
+		}
+		args = append(append([]string(nil), fields...), args[1:]...)
+	}
+	return args
+}
+
 // Dispatch selects a command based on args and invokes it.
 func (d *Dispatcher) Dispatch(args []string) error {
+	args = d.Expand(args)
 	if len(args) == 0 {
 		return fmt.Errorf("%w: default command pending", ErrNotYetImplemented)
 	}
@@ -64,3 +174,15 @@ func (d *Dispatcher) Lookup(name string) (Command, bool) {
 	cmd, ok := d.registry[name]
 	return cmd, ok
 }
+
+// shellAlias runs a "!" alias through the shell.
+type shellAlias struct {
+	name   string
+	script string
+}
+
+func (a *shellAlias) Name() string { return a.name }
+
+func (a *shellAlias) Execute(args []string) error {
+	return runner.Shell(context.Background(), a.script, args)
+}
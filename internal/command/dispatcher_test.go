@@ -94,3 +94,76 @@ func TestDispatcherRegisterAliasConflictPanics(t *testing.T) {
 	d.Register(&stubCommand{name: "primary"})
 	d.Register(&stubCommand{name: "secondary"}, "primary")
 }
+
+func TestDispatcherAliasExpandsWithArgs(t *testing.T) {
+	cmd := &stubCommand{name: "list"}
+	d := NewDispatcher()
+	d.Register(cmd)
+
+	if err := d.RegisterAliases(map[string]string{
+		"urls":  "list --type --verbose",
+		"short": "urls",
+	}); err != nil {
+		t.Fatalf("RegisterAliases error = %v", err)
+	}
+
+	if err := d.Dispatch([]string{"short", "--limit", "2"}); err != nil {
+		t.Fatalf("Dispatch error = %v", err)
+	}
+	want := []string{"--type", "--verbose", "--limit", "2"}
+	if len(cmd.args) != len(want) {
+		t.Fatalf("args = %q, want %q", cmd.args, want)
+	}
+	for i := range want {
+		if cmd.args[i] != want[i] {
+			t.Fatalf("args = %q, want %q", cmd.args, want)
+		}
+	}
+}
+
+func TestDispatcherAliasRejectsShadowing(t *testing.T) {
+	d := NewDispatcher()
+	d.Register(&stubCommand{name: "list"}, "ls")
+
+	for _, name := range []string{"list", "ls"} {
+		if err := d.RegisterAliases(map[string]string{name: "list --verbose"}); err == nil {
+			t.Fatalf("expected error when alias %q shadows a command", name)
+		}
+	}
+}
+
+func TestDispatcherAliasRejectsCycles(t *testing.T) {
+	d := NewDispatcher()
+	d.Register(&stubCommand{name: "list"})
+
+	err := d.RegisterAliases(map[string]string{
+		"a": "b --flag",
+		"b": "c",
+		"c": "a",
+	})
+	if err == nil {
+		t.Fatalf("expected error for alias cycle")
+	}
+
+	if _, ok := d.Lookup("a"); ok {
+		t.Fatalf("no aliases should be registered after a failed load")
+	}
+	if got := d.Expand([]string{"a"}); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("Expand = %q, want unchanged", got)
+	}
+}
+
+func TestDispatcherShellAliasRegistersCommand(t *testing.T) {
+	d := NewDispatcher()
+	if err := d.RegisterAliases(map[string]string{"hello": "!true"}); err != nil {
+		t.Fatalf("RegisterAliases error = %v", err)
+	}
+
+	cmd, ok := d.Lookup("hello")
+	if !ok {
+		t.Fatalf("expected shell alias to be looked up as a command")
+	}
+	if err := cmd.Execute([]string{"ignored"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+// DupesCommand lists snippets saved more than once, and merges them.
+type DupesCommand struct {
+	Deduper *services.Deduper
+	Input   io.Reader
+	Output  io.Writer
+}
+
+// NewDupesCommand constructs a DupesCommand using defaults from cfg.
+func NewDupesCommand(cfg Config) *DupesCommand {
+	var deduper *services.Deduper
+	if cfg.DB != nil {
+		deduper = &services.Deduper{
+			BaseDir: cfg.BaseDir,
+			DB:      cfg.DB,
+			Now:     cfg.clock(),
+			Hooks:   cfg.Hooks,
+		}
+	}
+	return &DupesCommand{
+		Deduper: deduper,
+		Input:   cfg.reader(),
+		Output:  cfg.writer(),
+	}
+}
+
+// Name returns the command keyword.
+func (c *DupesCommand) Name() string { return "dupes" }
+
+// Execute lists groups of identical snippets, offering to merge each with --merge.
+func (c *DupesCommand) Execute(args []string) error {
+	if c.Output == nil || c.Deduper == nil {
+		return errors.New("dupes command not fully configured")
+	}
+
+	fs := flag.NewFlagSet("dupes", flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var merge *bool = fs.BoolP("merge", "m", false, "ask which key of each group to keep, and merge the rest into it")
+	var force *bool = fs.BoolP("force", "f", false, "merge protected snippets too")
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow dupes [--merge] [--force]
+
+  wow! Lists snippets saved more than once under
+  different keys. With --merge, pick the key to
+  keep from each group: the tags of the others are
+  added to it, and the others are removed.
+  Groups holding a protected snippet are only
+  merged with --force.`)
+		fmt.Fprintln(c.Output)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	if len(fs.Args()) > 0 {
+		return errors.New("dupes takes no arguments")
+	}
+	if *merge && c.Input == nil {
+		return errors.New("dupes command not fully configured")
+	}
+
+	deduper := *c.Deduper
+	deduper.Force = *force
+
+	ctx := context.Background()
+	groups, err := deduper.Groups(ctx)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		_, err := fmt.Fprintln(c.Output, "no duplicates")
+		return err
+	}
+
+	answers := bufio.NewReader(c.Input)
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Fprintln(c.Output)
+		}
+		writeDupeGroup(c.Output, group)
+		if !*merge {
+			continue
+		}
+		keep, ok, err := askKeep(answers, c.Output, len(group))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		var others []string
+		for j, meta := range group {
+			if j != keep {
+				others = append(others, meta.Key)
+			}
+		}
+		if _, err := deduper.Merge(ctx, group[keep].Key, others); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.Output, "kept %s; removed %s\n", group[keep].Key, strings.Join(others, ", "))
+	}
+
+	if !*merge {
+		fmt.Fprintln(c.Output)
+		_, err := fmt.Fprintln(c.Output, `Run "wow dupes --merge" to merge them.`)
+		return err
+	}
+	return nil
+}
+
+func writeDupeGroup(w io.Writer, group []model.Metadata) {
+	fmt.Fprintf(w, "%d copies of %s:\n", len(group), formatSize(group[0].Size))
+	for i, meta := range group {
+		line := fmt.Sprintf("  %d) %s", i+1, meta.Key)
+		if meta.Tags != "" {
+			line += " [" + meta.Tags + "]"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// askKeep asks which of n keys to keep. It reports false when the
+// group is skipped.
+func askKeep(answers *bufio.Reader, out io.Writer, n int) (int, bool, error) {
+	for {
+		fmt.Fprintf(out, "keep which? [1-%d, enter to skip] ", n)
+		answer, err := answers.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, false, err
+		}
+		answer = strings.TrimSpace(answer)
+		if answer == "" || strings.EqualFold(answer, "s") {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(out)
+			}
+			return 0, false, nil
+		}
+		if choice, convErr := strconv.Atoi(answer); convErr == nil && choice >= 1 && choice <= n {
+			return choice - 1, true, nil
+		}
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(out)
+			return 0, false, nil
+		}
+		fmt.Fprintf(out, "%q is not a number from 1 to %d\n", answer, n)
+	}
+}
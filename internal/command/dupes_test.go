@@ -0,0 +1,82 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/services"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func seedDupes(t *testing.T, saver *services.Saver) {
+	t.Helper()
+	for _, sn := range []struct {
+		key  string
+		tags []string
+	}{
+		{"k8s/pods", []string{"k8s"}},
+		{"kube/pods", []string{"kubectl"}},
+	} {
+		req := services.SaveRequest{Key: sn.key, Tags: sn.tags, Reader: strings.NewReader("kubectl get pods -A\n")}
+		if _, err := saver.Save(context.Background(), req); err != nil {
+			t.Fatalf("Save(%s) error = %v", sn.key, err)
+		}
+	}
+}
+
+func TestDupesCommandListsGroups(t *testing.T) {
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+	seedDupes(t, saver)
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	if err := NewDupesCommand(cfg).Execute(nil); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+
+	want := `2 copies of 20 B:
+  1) k8s/pods [k8s]
+  2) kube/pods [kubectl]
+
+Run "wow dupes --merge" to merge them.
+`
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestDupesCommandMergesChosenKey(t *testing.T) {
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+	seedDupes(t, saver)
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	cfg.Input = strings.NewReader("7\n2\n")
+	if err := NewDupesCommand(cfg).Execute([]string{"--merge"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+
+	if !strings.Contains(out.String(), `"7" is not a number from 1 to 2`) {
+		t.Fatalf("output = %q, want the bad answer pointed out", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "kept kube/pods; removed k8s/pods\n") {
+		t.Fatalf("output = %q, want a merge report", out.String())
+	}
+
+	ctx := context.Background()
+	meta, err := storage.GetMetadata(ctx, cfg.DB, "kube/pods")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if meta.Tags != "kubectl,k8s" {
+		t.Fatalf("Tags = %q, want kubectl,k8s", meta.Tags)
+	}
+	if _, err := storage.GetMetadata(ctx, cfg.DB, "k8s/pods"); !errors.Is(err, storage.ErrMetadataNotFound) {
+		t.Fatalf("k8s/pods should be removed, got %v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package command
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	flag "github.com/spf13/pflag"
 
@@ -14,12 +17,14 @@ import (
 
 // EditHandler wraps the edit behaviour required by the command.
 type EditHandler interface {
-	Edit(ctx context.Context, key string) (model.Metadata, error)
+	Edit(ctx context.Context, key string, opts services.EditOptions) (model.Metadata, error)
+	EditMany(ctx context.Context, patterns []string, opts services.EditOptions) ([]services.EditResult, error)
 }
 
-// EditCommand opens an existing snippet in the user's editor.
+// EditCommand opens existing snippets in the user's editor.
 type EditCommand struct {
 	Editor EditHandler
+	Output io.Writer
 }
 
 // NewEditCommand constructs an EditCommand using defaults from cfg.
@@ -30,37 +35,228 @@ func NewEditCommand(cfg Config) *EditCommand {
 			DB:      cfg.DB,
 			Now:     cfg.clock(),
 			Open:    cfg.editor(),
+			Resolve: promptConflict(cfg.reader(), os.Stderr),
+			Hooks:   cfg.Hooks,
+
+			CompressAbove: cfg.CompressAbove,
+			Passphrase:    cfg.passphrase(false),
+			Secrets:       cfg.Secrets,
+			Warn:          warnSecrets(os.Stderr, cfg.Secrets),
 		},
+		Output: cfg.writer(),
 	}
 }
 
 // Name returns the command keyword.
 func (c *EditCommand) Name() string { return "edit" }
 
-// Execute edits the snippet identified by key.
+// Execute edits the snippets identified by the keys or patterns in args.
 func (c *EditCommand) Execute(args []string) error {
 	if c.Editor == nil {
 		return errors.New("edit command not configured")
 	}
 
 	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
-	fs.SetOutput(os.Stdout)
+	fs.SetOutput(c.writer())
+	var meta *bool = fs.BoolP("meta", "m", false, "edit description, tags, type and custom fields as YAML")
+	var all *bool = fs.BoolP("all", "a", false, "edit the metadata header and the content together")
+	line, search := positionFlags(fs)
+	var force *bool = fs.BoolP("force", "f", false, "edit snippets even if they are protected")
 	var help *bool = fs.BoolP("help", "h", false, "display help")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
 	if *help {
-		fmt.Fprintln(os.Stdout, `Usage:
-  wow edit <key>`)
+		fmt.Fprintln(c.writer(), `Usage:
+  wow edit <key>[:line] [--line n | --search regex] [--meta | --all]
+  wow edit <key|prefix/|glob>... [--meta | --all]
+
+  wow! Opens snippets in your editor.
+
+  Several keys, a prefix ending in "/" or a glob
+  such as 'k8s/*' open together in one session.
+  Each file is then saved on its own, and wow!
+  shows what changed.
+
+  The editor works on a copy. If the snippet
+  changes on disk before you save, you can merge,
+  overwrite or abort.
+
+  Some examples:
+    wow edit notes/deploy:42     -->  opens "notes/deploy" at line 42.
+    wow edit notes/ --meta       -->  edits the metadata of "notes/".
+    wow edit query --search JOIN -->  opens "query" at the first JOIN.
+
+  With --meta, the metadata is edited as a
+  front-matter block:
+
+    ---
+    description: deploy notes
+    tags: [ops, k8s]
+    type: text
+    owner: sam
+    ---
+
+  Fields other than description, tags and type
+  are kept as custom fields. If the block does
+  not parse, the editor opens again with the
+  error noted at the top.
+
+  A line is passed on in the way your editor
+  expects, for vim, nano, emacs, VS Code and
+  others. Add templates for other programs to
+  config.toml:
+
+    [line]
+    myeditor = "--goto {path}:{line}"
+
+  Protected snippets are only edited with --force.`)
+		fmt.Fprintln(c.writer())
 		fs.PrintDefaults()
 		return nil
 	}
 
 	remaining := fs.Args()
-	if len(remaining) != 1 {
-		return errors.New("edit expects exactly one key")
+	if len(remaining) == 0 {
+		return errors.New("edit expects at least one key")
+	}
+
+	opts := services.EditOptions{Mode: services.EditContent, Force: *force}
+	switch {
+	case *all:
+		opts.Mode = services.EditAll
+	case *meta:
+		opts.Mode = services.EditMeta
+	}
+
+	if len(remaining) == 1 && !isPattern(remaining[0]) {
+		k, at, err := splitPosition(remaining[0], *line, *search)
+		if err != nil {
+			return err
+		}
+		opts.At = at
+		_, err = c.Editor.Edit(context.Background(), k, opts)
+		return err
+	}
+
+	if *line != 0 || *search != "" {
+		return errors.New("--line and --search apply to a single key")
+	}
+	results, err := c.Editor.EditMany(context.Background(), remaining, opts)
+	if err != nil {
+		return err
+	}
+	return c.printSummary(results)
+}
+
+// isPattern reports whether arg names several snippets.
+func isPattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[") || strings.HasSuffix(arg, "/")
+}
+
+// writer returns Output, or stdout when it is unset.
+func (c *EditCommand) writer() io.Writer {
+	if c.Output == nil {
+		return os.Stdout
+	}
+	return c.Output
+}
+
+// printSummary lists each snippet of a batch edit with its outcome.
+func (c *EditCommand) printSummary(results []services.EditResult) error {
+	out := c.writer()
+
+	failed := 0
+	for _, res := range results {
+		status := "unchanged"
+		switch {
+		case res.Err != nil:
+			status = "failed"
+			failed++
+		case res.Changed:
+			status = "changed"
+		}
+
+		line := fmt.Sprintf("%-9s  %s", status, res.Key)
+		if res.Err != nil {
+			line += ": " + res.Err.Error()
+		}
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d snippets not saved", failed, len(results))
+	}
+	return nil
+}
+
+// promptConflict shows how a snippet changed underneath an edit and asks
+// what to do about it. Anything but merge or overwrite aborts.
+func promptConflict(in io.Reader, out io.Writer) func(context.Context, services.Conflict) (services.Resolution, error) {
+	answers := bufio.NewReader(in)
+	return func(_ context.Context, c services.Conflict) (services.Resolution, error) {
+		fmt.Fprintf(out, "%q changed on disk while you were editing:\n", c.Key)
+		fmt.Fprintln(out, "--- on disk")
+		fmt.Fprintln(out, "+++ edited")
+		for _, line := range lineDiff(string(c.OnDisk), string(c.Edited)) {
+			fmt.Fprintln(out, line)
+		}
+		fmt.Fprint(out, "[m]erge, [o]verwrite, [a]bort? ")
+
+		answer, err := answers.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return services.ResolveAbort, err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "m", "merge":
+			return services.ResolveMerge, nil
+		case "o", "overwrite":
+			return services.ResolveOverwrite, nil
+		default:
+			return services.ResolveAbort, nil
+		}
+	}
+}
+
+// lineDiff returns the lines of a and b prefixed with "-", "+" or " ",
+// aligned on their longest common subsequence.
+func lineDiff(a, b string) []string {
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// lcs[i][j] is the common subsequence length of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, " "+x[i])
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+x[i])
+			i++
+		default:
+			lines = append(lines, "+"+y[j])
+			j++
+		}
 	}
-	_, err := c.Editor.Edit(context.Background(), remaining[0])
-	return err
+	return lines
 }
@@ -1,11 +1,14 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/services"
 )
 
 func TestEditCommandName(t *testing.T) {
@@ -22,17 +25,13 @@ func TestEditCommandRequiresEditor(t *testing.T) {
 	}
 }
 
-func TestEditCommandRequiresSingleKey(t *testing.T) {
+func TestEditCommandRequiresKey(t *testing.T) {
 	editor := &stubEditor{}
 	cmd := &EditCommand{Editor: editor}
 
 	if err := cmd.Execute(nil); err == nil {
 		t.Fatalf("expected error for missing key")
 	}
-
-	if err := cmd.Execute([]string{"one", "two"}); err == nil {
-		t.Fatalf("expected error for too many args")
-	}
 }
 
 func TestEditCommandCallsEditor(t *testing.T) {
@@ -70,14 +69,137 @@ func TestEditCommandPropagatesErrors(t *testing.T) {
 }
 
 type stubEditor struct {
-	edit   func(ctx context.Context, key string) (model.Metadata, error)
-	called bool
+	edit     func(ctx context.Context, key string) (model.Metadata, error)
+	called   bool
+	opts     services.EditOptions
+	patterns []string
+	results  []services.EditResult
+}
+
+func (s *stubEditor) EditMany(ctx context.Context, patterns []string, opts services.EditOptions) ([]services.EditResult, error) {
+	s.called = true
+	s.opts = opts
+	s.patterns = patterns
+	return s.results, nil
 }
 
-func (s *stubEditor) Edit(ctx context.Context, key string) (model.Metadata, error) {
+func (s *stubEditor) Edit(ctx context.Context, key string, opts services.EditOptions) (model.Metadata, error) {
 	s.called = true
+	s.opts = opts
 	if s.edit == nil {
 		return model.Metadata{}, nil
 	}
 	return s.edit(ctx, key)
 }
+
+func TestPromptConflictReadsChoice(t *testing.T) {
+	var out bytes.Buffer
+	resolve := promptConflict(strings.NewReader("o\nm\n\n"), &out)
+	conflict := services.Conflict{Key: "notes/a", Edited: []byte("mine\n"), OnDisk: []byte("theirs\n")}
+
+	want := []services.Resolution{services.ResolveOverwrite, services.ResolveMerge, services.ResolveAbort, services.ResolveAbort}
+	for i, w := range want {
+		got, err := resolve(context.Background(), conflict)
+		if err != nil {
+			t.Fatalf("resolve error = %v", err)
+		}
+		if got != w {
+			t.Fatalf("answer %d = %v, want %v", i, got, w)
+		}
+	}
+
+	if !strings.Contains(out.String(), "-theirs\n+mine\n") {
+		t.Fatalf("output missing diff: %q", out.String())
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	got := strings.Join(lineDiff("a\nb\nc\n", "a\nx\nc\n"), "\n")
+	want := " a\n-b\n+x\n c"
+	if got != want {
+		t.Fatalf("lineDiff = %q, want %q", got, want)
+	}
+}
+
+func TestEditCommandModeFlags(t *testing.T) {
+	cases := map[string]services.EditMode{
+		"":       services.EditContent,
+		"--meta": services.EditMeta,
+		"-m":     services.EditMeta,
+		"--all":  services.EditAll,
+	}
+	for flag, want := range cases {
+		editor := &stubEditor{}
+		cmd := &EditCommand{Editor: editor}
+		args := []string{"key"}
+		if flag != "" {
+			args = append(args, flag)
+		}
+		if err := cmd.Execute(args); err != nil {
+			t.Fatalf("Execute(%v) error = %v", args, err)
+		}
+		if editor.opts.Mode != want {
+			t.Fatalf("Execute(%v) mode = %v, want %v", args, editor.opts.Mode, want)
+		}
+	}
+}
+
+func TestEditCommandPassesLine(t *testing.T) {
+	var gotKey string
+	editor := &stubEditor{
+		edit: func(ctx context.Context, key string) (model.Metadata, error) {
+			gotKey = key
+			return model.Metadata{}, nil
+		},
+	}
+	cmd := &EditCommand{Editor: editor}
+	if err := cmd.Execute([]string{"ops/deploy:120"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if gotKey != "ops/deploy" || editor.opts.At.Line != 120 {
+		t.Fatalf("key = %q, at = %+v", gotKey, editor.opts.At)
+	}
+}
+
+func TestEditCommandBatchPrintsSummary(t *testing.T) {
+	editor := &stubEditor{results: []services.EditResult{
+		{Key: "k8s/deploy", Changed: true},
+		{Key: "k8s/service"},
+	}}
+	var out bytes.Buffer
+	cmd := &EditCommand{Editor: editor, Output: &out}
+
+	if err := cmd.Execute([]string{"k8s/*", "ops/deploy"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if len(editor.patterns) != 2 || editor.patterns[0] != "k8s/*" {
+		t.Fatalf("patterns = %v", editor.patterns)
+	}
+	want := "changed    k8s/deploy\nunchanged  k8s/service\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestEditCommandBatchReportsFailures(t *testing.T) {
+	editor := &stubEditor{results: []services.EditResult{
+		{Key: "k8s/deploy", Err: errors.New("boom")},
+		{Key: "k8s/service", Changed: true},
+	}}
+	var out bytes.Buffer
+	cmd := &EditCommand{Editor: editor, Output: &out}
+
+	if err := cmd.Execute([]string{"k8s/"}); err == nil {
+		t.Fatalf("expected error when a snippet fails")
+	}
+	if !strings.Contains(out.String(), "failed     k8s/deploy: boom") {
+		t.Fatalf("output = %q", out.String())
+	}
+}
+
+func TestEditCommandBatchRejectsLine(t *testing.T) {
+	cmd := &EditCommand{Editor: &stubEditor{}}
+	if err := cmd.Execute([]string{"k8s/*", "--line", "3"}); err == nil {
+		t.Fatalf("expected error for --line with several snippets")
+	}
+}
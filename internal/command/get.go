@@ -1,25 +1,40 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
 
+	"github.com/charmbracelet/x/ansi"
 	flag "github.com/spf13/pflag"
+	"golang.org/x/term"
 
 	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/model"
 	"github.com/llywelwyn/wow/internal/services"
 	"github.com/llywelwyn/wow/internal/storage"
 	"github.com/llywelwyn/wow/internal/ui"
+	"github.com/llywelwyn/wow/internal/vault"
 )
 
 // GetCommand streams snippet content to stdout and optionally mutates tags.
+//
+// When Output is a terminal, code is syntax highlighted, and a snippet
+// taller than the screen is piped through Pager, like git does.
 type GetCommand struct {
 	BaseDir string
 	Output  io.Writer
 	Meta    *services.Metadata
+	Vaults  vault.Stack
+	Pager   func(context.Context, io.Reader) error // nil never pages.
+	Screen  func() (width, height int, ok bool)    // defaults to the size of Output; ok when it is a terminal.
+
+	Passphrase storage.Passphrase      // for encrypted snippets.
+	Secrets    *services.SecretScanner // what --redact masks; defaults to the built-in rules.
+	Redact     bool                    // masks secrets on a terminal without --redact.
 }
 
 // NewGetCommand constructs a GetCommand using defaults from cfg.
@@ -35,6 +50,12 @@ func NewGetCommand(cfg Config) *GetCommand {
 		BaseDir: cfg.BaseDir,
 		Output:  cfg.writer(),
 		Meta:    meta,
+		Vaults:  cfg.vaults(),
+		Pager:   cfg.PageOutput,
+
+		Passphrase: cfg.passphrase(false),
+		Secrets:    cfg.secretScanner(),
+		Redact:     cfg.Redact,
 	}
 }
 
@@ -55,6 +76,10 @@ func (c *GetCommand) Execute(args []string) error {
 	fs.SetOutput(c.Output)
 	var addCSV *string = fs.StringP("tag", "t", "", "comma-separated tags to add")
 	var removeCSV *string = fs.StringP("untag", "u", "", "comma-separated tags to remove")
+	var noPager *bool = fs.Bool("no-pager", false, "never page output, even on a terminal")
+	var noHighlight *bool = fs.Bool("no-highlight", false, "never colour code, even on a terminal")
+	var force *bool = fs.BoolP("force", "f", false, "print binary snippets to a terminal, or retag protected ones")
+	fs.Bool("redact", false, redactUsage)
 	var help *bool = fs.BoolP("help", "h", false, "display help")
 
 	if len(tagArgs.Others) == 0 {
@@ -66,32 +91,8 @@ func (c *GetCommand) Execute(args []string) error {
 		if err := fs.Parse(tagArgs.Others); err != nil {
 			return err
 		}
-		// TODO: This is completely duplicated. Dedupe this with a refactor of parsing --help on implicit Gets.
 		if *help {
-			fmt.Fprintln(c.Output, `Usage:
-  wow get <key> [--tag tag1,tag2] [--untag tag1] [@tag1 @tag2] [-@tag1]
-
-  wow! Fetches a snippet, or modifies its metadata.
-
-  You can get implicitly by running "wow <key>"
-  without the "get" keyword. Provided no input
-  was piped in, wow! guesses you want to fetch.
-
-  If you add any flags, rather than outputting
-  the content of the snippet, wow! will update
-  the metadata instead. 
-
-  Some examples:
-    wow foo             -->  fetches the content of "foo".
-    wow foo @bar -@baz  -->  adds "bar" and removes "baz" from tags.
-    wow foo --tag 1,2   -->  adds "1" and "2" to tags.`)
-			fmt.Fprintln(c.Output)
-			fs.PrintDefaults()
-			fmt.Fprintln(c.Output, `
-  PS.
-    You wont be able to implicitly get if your
-    key collides with another command. In that
-    case, you need to specify: "wow get <key>"`)
+			c.printHelp(fs)
 			return nil
 		}
 		return errors.New("key must be the first argument")
@@ -102,7 +103,80 @@ func (c *GetCommand) Execute(args []string) error {
 	}
 
 	if *help {
-		fmt.Fprintln(c.Output, `Usage:
+		c.printHelp(fs)
+		return nil
+	}
+
+	addTags := append(splitTags(*addCSV), tagArgs.Add...)
+	removeTags := append(splitTags(*removeCSV), tagArgs.Remove...)
+	hasTagChange := len(addTags) > 0 || len(removeTags) > 0
+
+	found, path, err := c.resolve(keyArg)
+	if err != nil && (!hasTagChange || !errors.Is(err, storage.ErrNotFound)) {
+		return err
+	}
+
+	if !hasTagChange {
+		meta := c.metadata(found, keyArg)
+		data, err := storage.ReadDecrypted(path, meta.Encoding, c.Passphrase)
+		if err != nil {
+			return err
+		}
+		if meta.MIME == "" {
+			// Snippets saved before media types were recorded.
+			if detected := services.Detect(keyArg, data); detected.Type == "binary" {
+				meta.Type, meta.MIME = detected.Type, detected.MIME
+			}
+		}
+		width, height, tty := c.screen()
+		redact := redacting(fs, c.Redact, tty)
+		if meta.Type == "binary" && redact {
+			return fmt.Errorf("%q is binary (%s); it cannot be redacted", keyArg, meta.MIME)
+		}
+		if redact {
+			data = orDefaultRules(c.Secrets).Redact(data, services.HasSecretTag(meta.Tags))
+		}
+		if meta.Type == "binary" && tty {
+			if !*force {
+				return fmt.Errorf("%q is binary (%s, %s); pass --force to print it, or redirect it to a file",
+					keyArg, meta.MIME, formatSize(int64(len(data))))
+			}
+			_, err := c.Output.Write(data)
+			return err
+		}
+		page := !*noPager && c.Pager != nil && tty && height > 0 && screenLines(data, width) >= height
+		if tty && !*noHighlight {
+			data = ui.Highlight(data, path, meta.Language)
+		}
+		if page {
+			return c.Pager(context.Background(), bytes.NewReader(data))
+		}
+		if _, err := c.Output.Write(data); err != nil {
+			return fmt.Errorf("write snippet to output: %w", err)
+		}
+		return nil
+	}
+
+	if c.Meta == nil {
+		return errors.New("metadata updates not supported")
+	}
+	if found.ReadOnly {
+		return fmt.Errorf("%w: %q is in %q", vault.ErrReadOnly, keyArg, found.Name)
+	}
+
+	svc := *c.Meta
+	svc.Force = *force
+	result, err := svc.UpdateTags(context.Background(), keyArg, addTags, removeTags)
+	if err != nil {
+		return err
+	}
+
+	return writeTagSummary(c.Output, result.Added, result.Removed)
+}
+
+// printHelp writes the usage of get, with the flags of fs.
+func (c *GetCommand) printHelp(fs *flag.FlagSet) {
+	fmt.Fprintln(c.Output, `Usage:
   wow get <key> [--tag tag1,tag2] [--untag tag1] [@tag1 @tag2] [-@tag1]
 
   wow! Fetches a snippet, or modifies its metadata.
@@ -113,52 +187,97 @@ func (c *GetCommand) Execute(args []string) error {
 
   If you add any flags, rather than outputting
   the content of the snippet, wow! will update
-  the metadata instead. 
+  the metadata instead.
 
   Some examples:
     wow foo             -->  fetches the content of "foo".
     wow foo @bar -@baz  -->  adds "bar" and removes "baz" from tags.
-	wow foo --tag 1,2   -->  adds "1" and "2" to tags.`)
-		fmt.Fprintln(c.Output)
-		fs.PrintDefaults()
-		fmt.Fprintln(c.Output, `
+    wow foo --tag 1,2   -->  adds "1" and "2" to tags.
+
+  On a terminal, code is highlighted and snippets
+  taller than the screen open in your pager. Pass
+  --no-highlight or --no-pager to skip either.
+  Paging is turned off in config.toml with get =
+  false under [pager]. Binary snippets are only
+  printed to a terminal with --force; redirect
+  them to a file instead.
+  The tags of protected snippets only change with
+  --force too.
+
+  --redact masks anything that looks like a secret,
+  and the values of snippets tagged @secret, so you
+  can share your screen. With redact = true under
+  [secrets] in config.toml it is the default on a
+  terminal; output is only masked when piped if you
+  pass --redact.`)
+	fmt.Fprintln(c.Output)
+	fs.PrintDefaults()
+	fmt.Fprintln(c.Output, `
   PS.
     You wont be able to implicitly get if your
     key collides with another command. In that
     case, you need to specify: "wow get <key>"`)
-		return nil
-	}
+}
 
-	addTags := append(splitTags(*addCSV), tagArgs.Add...)
-	removeTags := append(splitTags(*removeCSV), tagArgs.Remove...)
-	hasTagChange := len(addTags) > 0 || len(removeTags) > 0
+// screen reports the size of Output, and whether it is a terminal.
+func (c *GetCommand) screen() (int, int, bool) {
+	if c.Screen != nil {
+		return c.Screen()
+	}
+	return terminalSize(c.Output)
+}
 
-	path, err := key.ResolvePath(c.BaseDir, keyArg)
+// metadata returns what is stored for the snippet, or nothing when
+// it cannot be read.
+func (c *GetCommand) metadata(found vault.Vault, rawKey string) model.Metadata {
+	if found.DB == nil {
+		return model.Metadata{}
+	}
+	normalized, err := key.Normalize(rawKey)
 	if err != nil {
-		return err
+		return model.Metadata{}
+	}
+	meta, err := storage.GetMetadata(context.Background(), found.DB, normalized)
+	if err != nil {
+		return model.Metadata{}
 	}
+	return meta
+}
 
-	if !hasTagChange {
-		data, err := storage.Read(path)
-		if err != nil {
-			return err
-		}
-		if _, err := c.Output.Write(data); err != nil {
-			return fmt.Errorf("write snippet to output: %w", err)
+// screenLines counts the rows data fills on a screen width columns wide.
+// Colour escapes take no room.
+func screenLines(data []byte, width int) int {
+	rows := 0
+	for line := range strings.SplitSeq(strings.TrimSuffix(string(data), "\n"), "\n") {
+		rows++
+		if n := ansi.StringWidth(line); width > 0 && n > width {
+			rows += (n - 1) / width
 		}
-		return nil
 	}
+	return rows
+}
 
-	if c.Meta == nil {
-		return errors.New("metadata updates not supported")
+// terminalSize reports the size of w when it is a terminal.
+func terminalSize(w io.Writer) (int, int, bool) {
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok || !writerIsTerminal(w) {
+		return 0, 0, false
 	}
-
-	result, err := c.Meta.UpdateTags(context.Background(), keyArg, addTags, removeTags)
+	width, height, err := term.GetSize(int(f.Fd()))
 	if err != nil {
-		return err
+		return 0, 0, false
 	}
+	return width, height, true
+}
 
-	return writeTagSummary(c.Output, result.Added, result.Removed)
+// resolve finds the snippet file across the vault search path.
+// Without configured vaults it falls back to BaseDir alone.
+func (c *GetCommand) resolve(rawKey string) (vault.Vault, string, error) {
+	if len(c.Vaults) > 0 {
+		return c.Vaults.Resolve(rawKey)
+	}
+	path, err := key.ResolvePath(c.BaseDir, rawKey)
+	return vault.Vault{Name: "user", BaseDir: c.BaseDir}, path, err
 }
 
 func writeTagSummary(w io.Writer, added, removed []string) error {
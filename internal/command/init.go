@@ -0,0 +1,104 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/config"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// InitCommand creates a project vault.
+type InitCommand struct {
+	Home   string
+	Output io.Writer
+	Getwd  func() (string, error)
+}
+
+// NewInitCommand constructs an InitCommand using defaults from cfg.
+func NewInitCommand(cfg Config) *InitCommand {
+	return &InitCommand{
+		Home:   cfg.Home,
+		Output: cfg.writer(),
+		Getwd:  os.Getwd,
+	}
+}
+
+// Name returns the command keyword.
+func (c *InitCommand) Name() string { return "init" }
+
+// Execute creates a .wow directory and its metadata DB in the target directory.
+func (c *InitCommand) Execute(args []string) error {
+	if c.Output == nil || c.Getwd == nil {
+		return errors.New("init command not fully configured")
+	}
+
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow init [dir]
+
+  wow! Creates a project vault in dir, or the working
+  directory. Commit it alongside your code to share
+  runbooks and queries that belong to the repository.
+
+  Anywhere under dir, the project vault is searched
+  before your user vault and receives new snippets.
+  Put --global or --local before any command to
+  choose where writes go, e.g. "wow --global ls".`)
+		fmt.Fprintln(c.Output)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	remaining := fs.Args()
+	if len(remaining) > 1 {
+		return errors.New("init expects at most one directory")
+	}
+
+	dir := ""
+	if len(remaining) == 1 {
+		dir = remaining[0]
+	} else {
+		wd, err := c.Getwd()
+		if err != nil {
+			return fmt.Errorf("resolve working directory: %w", err)
+		}
+		dir = wd
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolve path %q: %w", dir, err)
+	}
+	vaultDir := filepath.Join(abs, config.ProjectDirName)
+
+	if c.Home != "" && filepath.Clean(c.Home) == vaultDir {
+		return fmt.Errorf("%q is your user vault", vaultDir)
+	}
+	if _, err := os.Stat(vaultDir); err == nil {
+		return fmt.Errorf("project vault already exists at %q", vaultDir)
+	}
+
+	db, err := storage.InitMetaDB(filepath.Join(vaultDir, "meta.db"))
+	if err != nil {
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.Output, "created project vault in %s\n", vaultDir)
+	return err
+}
@@ -0,0 +1,44 @@
+package command
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitCommandCreatesProjectVault(t *testing.T) {
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	cmd := &InitCommand{
+		Output: &out,
+		Getwd:  func() (string, error) { return dir, nil },
+	}
+
+	if err := cmd.Execute(nil); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".wow", "meta.db")); err != nil {
+		t.Fatalf("expected meta.db in project vault: %v", err)
+	}
+
+	if err := cmd.Execute(nil); err == nil {
+		t.Fatalf("expected error when project vault already exists")
+	}
+}
+
+func TestInitCommandRefusesUserVault(t *testing.T) {
+	home := t.TempDir()
+
+	cmd := &InitCommand{
+		Home:   filepath.Join(home, ".wow"),
+		Output: &bytes.Buffer{},
+		Getwd:  func() (string, error) { return home, nil },
+	}
+
+	if err := cmd.Execute(nil); err == nil {
+		t.Fatalf("expected error when targeting the user vault")
+	}
+}
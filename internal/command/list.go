@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,14 +16,20 @@ import (
 	"golang.org/x/term"
 
 	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/services"
 	"github.com/llywelwyn/wow/internal/storage"
 	"github.com/llywelwyn/wow/internal/ui"
+	"github.com/llywelwyn/wow/internal/vault"
 )
 
 // ListCommand prints snippet metadata.
 type ListCommand struct {
 	DB     *sql.DB
 	Output io.Writer
+	Vaults vault.Stack
+
+	Secrets *services.SecretScanner // what --redact masks; defaults to the built-in rules.
+	Redact  bool                    // masks secrets on a terminal without --redact.
 }
 
 type listViewOptions struct {
@@ -30,6 +37,9 @@ type listViewOptions struct {
 	WithDates  bool
 	WithDesc   bool
 	WithType   bool
+	WithVault  bool
+	WithSize   bool
+	WithLock   bool
 	Limit      int
 	Page       int
 	TotalItems int
@@ -41,6 +51,10 @@ func NewListCommand(cfg Config) *ListCommand {
 	return &ListCommand{
 		DB:     cfg.DB,
 		Output: cfg.writer(),
+		Vaults: cfg.vaults(),
+
+		Secrets: cfg.secretScanner(),
+		Redact:  cfg.Redact,
 	}
 }
 
@@ -63,10 +77,14 @@ func (c *ListCommand) Execute(args []string) error {
 	var withDates *bool = fs.BoolP("dates", "D", false, "include created/updated dates")
 	var withDesc *bool = fs.BoolP("desc", "d", false, "include descriptions")
 	var withType *bool = fs.BoolP("type", "T", false, "include snippet type")
+	var withVault *bool = fs.BoolP("vault", "V", false, "include the vault each snippet comes from")
+	var withSize *bool = fs.BoolP("size", "s", false, "include size and media type")
+	var withLock *bool = fs.BoolP("protected", "P", false, "include whether each snippet is protected, in plain output")
 	var all *bool = fs.BoolP("all", "a", false, "overrides --limit and any defaults, showing every listing")
 	var verbose *bool = fs.BoolP("verbose", "v", false, "show all metadata fields")
 	var limit *int = fs.IntP("limit", "l", 50, "maximum number of snippets to display per page")
 	var page *int = fs.IntP("page", "p", 1, "page number (1-based)")
+	fs.Bool("redact", false, redactUsage)
 	var help *bool = fs.BoolP("help", "h", false, "display help")
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -75,7 +93,8 @@ func (c *ListCommand) Execute(args []string) error {
 	if *help {
 		fmt.Fprintln(c.Output, `Usage:
   wow list [--limit int] [--page int] [--plain] [--verbose]
-           [--tags] [--type] [--desc] [--dates] [--all]
+           [--tags] [--type] [--desc] [--dates] [--vault] [--size] [--all]
+           [--protected] [--redact]
 
   wow! Lists metadata for all the snippets you've got saved.
   It's modular, with support for pagination, and tabular or
@@ -92,10 +111,20 @@ func (c *ListCommand) Execute(args []string) error {
   Without any extra flags, it displays a list of saved keys
   only. With --verbose or -v, all metadata fields are shown.
   Individual flags can be used for more granular control.
+  --verbose only shows vaults when more than one is layered.
 
   Use --plain for tabular output to make writing scripts to
   parse lists easier. You can replace tabs with a different
-  delimiter by passing any string as an argument.`)
+  delimiter by passing any string as an argument. Protected
+  snippets show a lock; in plain output, --protected or
+  --verbose adds a column saying "protected" for them.
+  Size, media type and protection come after the
+  description, and snippets saved before sizes were kept
+  show "unknown".
+
+  --redact masks secrets in descriptions, as it does for
+  "wow get". It is the default on a terminal with redact =
+  true under [secrets] in config.toml.`)
 		fmt.Fprintln(c.Output)
 		fs.PrintDefaults()
 		return nil
@@ -112,7 +141,11 @@ func (c *ListCommand) Execute(args []string) error {
 	}
 
 	ctx := context.Background()
-	entries, err := storage.ListMetadata(ctx, c.DB)
+	stack := c.Vaults
+	if len(stack) == 0 {
+		stack = vault.Stack{{Name: "user", DB: c.DB}}
+	}
+	entries, err := stack.List(ctx)
 	if err != nil {
 		return err
 	}
@@ -126,6 +159,9 @@ func (c *ListCommand) Execute(args []string) error {
 		WithDates: *withDates || *verbose,
 		WithDesc:  *withDesc || *verbose,
 		WithType:  *withType || *verbose,
+		WithVault: *withVault || (*verbose && len(stack) > 1),
+		WithSize:  *withSize || *verbose,
+		WithLock:  *withLock || *verbose,
 		Limit:     actualLimit,
 		Page:      *page,
 	}
@@ -149,6 +185,12 @@ func (c *ListCommand) Execute(args []string) error {
 		opts.Page = 1
 	}
 	entries = paginateEntries(entries, opts.Limit, opts.Page)
+	if redacting(fs, c.Redact, writerIsTerminal(c.Output)) {
+		scanner := orDefaultRules(c.Secrets)
+		for i := range entries {
+			entries[i].Description = string(scanner.Redact([]byte(entries[i].Description), false))
+		}
+	}
 
 	if *plain != "" || !writerIsTerminal(c.Output) {
 		delimiter := *plain
@@ -175,7 +217,10 @@ func renderPlainList(w io.Writer, entries []model.Metadata, opts listViewOptions
 	for _, meta := range entries {
 		fields := []string{meta.Key}
 		if opts.WithType {
-			fields = append(fields, meta.Type)
+			fields = append(fields, meta.TypeLabel())
+		}
+		if opts.WithVault {
+			fields = append(fields, meta.Vault)
 		}
 		if opts.WithTags {
 			fields = append(fields, plainTagList(meta.Tags))
@@ -191,6 +236,23 @@ func renderPlainList(w io.Writer, entries []model.Metadata, opts listViewOptions
 		if opts.WithDesc {
 			fields = append(fields, meta.Description)
 		}
+		// Newer columns follow the description, so scripts reading the
+		// older ones by position keep working.
+		if opts.WithSize {
+			// Snippets saved before sizes were recorded have no media type.
+			size, mime := strconv.FormatInt(meta.Size, 10), meta.MIME
+			if mime == "" {
+				size, mime = "unknown", "unknown"
+			}
+			fields = append(fields, size, mime)
+		}
+		if opts.WithLock {
+			lock := ""
+			if meta.Protected {
+				lock = "protected"
+			}
+			fields = append(fields, lock)
+		}
 
 		if _, err := fmt.Fprintln(w, strings.Join(fields, delimiter)); err != nil {
 			return err
@@ -200,7 +262,7 @@ func renderPlainList(w io.Writer, entries []model.Metadata, opts listViewOptions
 }
 
 func makeFlagsString(opts listViewOptions) string {
-	if opts.WithDates && opts.WithDesc && opts.WithTags && opts.WithType {
+	if opts.WithDates && opts.WithDesc && opts.WithTags && opts.WithType && opts.WithSize {
 		return "viewing all metadata"
 	}
 
@@ -211,9 +273,15 @@ func makeFlagsString(opts listViewOptions) string {
 	if opts.WithType {
 		flags = append(flags, "type")
 	}
+	if opts.WithVault {
+		flags = append(flags, "vault")
+	}
 	if opts.WithDates {
 		flags = append(flags, "dates")
 	}
+	if opts.WithSize {
+		flags = append(flags, "sizes")
+	}
 	if opts.WithDesc {
 		flags = append(flags, "descriptions")
 	}
@@ -271,21 +339,28 @@ func renderStyledList(w io.Writer, entries []model.Metadata, opts listViewOption
 	return nil
 }
 
+// lockIcon follows the keys of protected snippets.
+const lockIcon = "🔒"
+
 func buildKeyLine(meta model.Metadata, styles ui.Styles, opts listViewOptions) string {
+	line := styles.Key.Render(meta.Key)
+	if meta.Protected {
+		line = fmt.Sprintf("%s %s", line, styles.Icon.Render(lockIcon))
+	}
 	if opts.WithType {
 		icon := strings.TrimSpace(meta.TypeIcon())
 		if icon != "" {
-			return fmt.Sprintf("%s %s",
-				styles.Icon.Render(icon),
-				styles.Key.Render(meta.Key),
-			)
+			return fmt.Sprintf("%s %s", styles.Icon.Render(icon), line)
 		}
 	}
-	return styles.Key.Render(meta.Key)
+	return line
 }
 
 func buildRootLine(meta model.Metadata, styles ui.Styles, wrap lipgloss.Style, opts listViewOptions) string {
 	base := buildKeyLine(meta, styles, opts)
+	if opts.WithVault && meta.Vault != "" {
+		base = fmt.Sprintf("%s %s", base, styles.Subtle.Render("("+meta.Vault+")"))
+	}
 	if opts.WithTags {
 		if tags := styledTagList(meta.Tags, styles); tags != "" {
 			base = fmt.Sprintf("%s %s", base, tags)
@@ -340,6 +415,10 @@ func renderStyledEntry(meta model.Metadata, width int, styles ui.Styles, opts li
 		}
 	}
 
+	if opts.WithSize && meta.MIME != "" {
+		t.Child(childWrap.Render(buildSizeLine(meta, styles)))
+	}
+
 	if opts.WithDesc {
 		if desc := strings.TrimSpace(meta.Description); desc != "" {
 			t.Child(childWrap.Render(styles.Subtle.Render(desc)))
@@ -361,6 +440,32 @@ func buildDateLine(meta model.Metadata, styles ui.Styles) string {
 	return strings.Join(components, "  ")
 }
 
+func buildSizeLine(meta model.Metadata, styles ui.Styles) string {
+	line := fmt.Sprintf("%s %s  %s %s",
+		styles.Label.Render("size"), styles.Subtle.Render(formatSize(meta.Size)),
+		styles.Label.Render("type"), styles.Subtle.Render(meta.MIME))
+	if meta.Encoding == storage.EncodingEncrypted {
+		line += "  " + styles.Label.Render("encrypted")
+	}
+	return line
+}
+
+// formatSize renders n bytes for people, as in "1.5 KB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
 func paginateEntries(entries []model.Metadata, limit, page int) []model.Metadata {
 	if limit <= 0 || len(entries) == 0 {
 		return entries
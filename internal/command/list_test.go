@@ -103,3 +103,99 @@ func TestListCommandLimitAndPage(t *testing.T) {
 		t.Fatalf("expected page 2 to show second, got %q", lines)
 	}
 }
+
+func TestListCommandSizeColumns(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cmd, cleanup := newListCommand(t, []model.Metadata{
+		{Key: "img/logo", Type: "binary", MIME: "image/png", Size: 2048, Created: now, Modified: now},
+	})
+	defer cleanup()
+
+	var out bytes.Buffer
+	cmd.Output = &out
+	if err := cmd.Execute([]string{"--plain", "--size"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if got := out.String(); got != "img/logo\t2048\timage/png\n" {
+		t.Fatalf("output = %q", got)
+	}
+}
+
+func TestListCommandPlainKeepsDescriptionColumn(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cmd, cleanup := newListCommand(t, []model.Metadata{
+		{Key: "notes", Type: "text", Description: "old notes", Created: now, Modified: now},
+	})
+	defer cleanup()
+
+	var out bytes.Buffer
+	cmd.Output = &out
+	if err := cmd.Execute([]string{"--plain", "--desc", "--size", "--protected"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if got := out.String(); got != "notes\told notes\tunknown\tunknown\t\n" {
+		t.Fatalf("output = %q", got)
+	}
+}
+
+func TestListCommandPlainProtectedColumn(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cmd, cleanup := newListCommand(t, []model.Metadata{
+		{Key: "runbooks/deploy", Type: "text", Created: now, Modified: now, Protected: true},
+		{Key: "notes", Type: "text", Created: now.Add(-time.Hour), Modified: now.Add(-time.Hour)},
+	})
+	defer cleanup()
+
+	var out bytes.Buffer
+	cmd.Output = &out
+	if err := cmd.Execute([]string{"--plain", "--protected"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if got := out.String(); got != "runbooks/deploy\tprotected\nnotes\t\n" {
+		t.Fatalf("output = %q", got)
+	}
+}
+
+func TestListCommandStyledLockIcon(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	entries := []model.Metadata{
+		{Key: "runbooks/deploy", Created: now, Modified: now, Protected: true},
+		{Key: "notes", Created: now, Modified: now},
+	}
+
+	var out bytes.Buffer
+	if err := renderStyledList(&out, entries, listViewOptions{}); err != nil {
+		t.Fatalf("renderStyledList error = %v", err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	var deploy, notes string
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, "runbooks/deploy"):
+			deploy = line
+		case strings.Contains(line, "notes"):
+			notes = line
+		}
+	}
+	if !strings.Contains(deploy, lockIcon) {
+		t.Fatalf("protected entry %q has no lock", deploy)
+	}
+	if strings.Contains(notes, lockIcon) {
+		t.Fatalf("unprotected entry %q has a lock", notes)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:           "0 B",
+		1023:        "1023 B",
+		1536:        "1.5 KB",
+		5 << 20:     "5.0 MB",
+		3 << 30 / 2: "1.5 GB",
+	}
+	for n, want := range tests {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
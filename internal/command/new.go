@@ -0,0 +1,135 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+// NewCommand composes a snippet in the user's editor.
+type NewCommand struct {
+	Composer *services.Composer
+	Output   io.Writer
+	Warn     io.Writer // where warnings go, such as duplicate content; nil drops them.
+}
+
+// NewNewCommand constructs a NewCommand using defaults from cfg.
+func NewNewCommand(cfg Config) *NewCommand {
+	var templates string
+	if cfg.Home != "" {
+		templates = filepath.Join(cfg.Home, "templates")
+	}
+	return &NewCommand{
+		Composer: &services.Composer{
+			Saver: &services.Saver{
+				BaseDir:       cfg.BaseDir,
+				DB:            cfg.DB,
+				Now:           cfg.clock(),
+				Hooks:         cfg.Hooks,
+				MaxSize:       cfg.MaxSnippetSize,
+				CompressAbove: cfg.CompressAbove,
+				Passphrase:    cfg.passphrase(true),
+				Secrets:       cfg.Secrets,
+			},
+			Open:      cfg.editor(),
+			Vaults:    cfg.vaults(),
+			Templates: templates,
+		},
+		Output: cfg.writer(),
+		Warn:   os.Stderr,
+	}
+}
+
+// Name returns the command keyword.
+func (c *NewCommand) Name() string { return "new" }
+
+// Execute opens the editor and saves what is written.
+func (c *NewCommand) Execute(args []string) error {
+	if c.Composer == nil || c.Output == nil {
+		return errors.New("new command not fully configured")
+	}
+
+	tagArgs := extractTagArgs(args)
+	args = tagArgs.Others
+
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var tee *bool = fs.BoolP("tee", "T", false, "print the snippet, rather than the key")
+	var desc *string = fs.StringP("desc", "d", "", "description")
+	var tags *string = fs.StringP("tag", "t", "", "comma-separated tags, e.g. one,two")
+	var from *string = fs.StringP("from", "f", "", "start from a snippet or a template in $WOW_HOME/templates")
+	var noDupes *bool = fs.Bool("no-dupes", false, "refuse content already saved under another key")
+	var encrypt *bool = fs.BoolP("encrypt", "e", false, "encrypt the snippet with a passphrase")
+	var noScan *bool = fs.Bool("no-scan", false, "save without scanning for secrets")
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+
+	var keyArg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		keyArg = args[0]
+		args = args[1:]
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow new [key] [--from key|template] [--desc description] [--tag tags] [@tag ...]
+
+Opens your editor on an empty file, or a copy of --from, and
+saves the result. Leave the file empty to save nothing.
+With --encrypt, the snippet is saved as ciphertext and
+your editor's copy is overwritten afterwards.`)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	req := services.ComposeRequest{
+		Key:         keyArg,
+		Description: *desc,
+		Tags:        append(splitTags(*tags), tagArgs.Add...),
+		From:        *from,
+		NoDupes:     *noDupes,
+		Encrypt:     *encrypt,
+		SkipScan:    *noScan,
+	}
+	var teeOut *teeWriter
+	if *tee {
+		teeOut = newTeeWriter(c.Output)
+		req.Tee = teeOut
+	}
+
+	res, err := c.Composer.Compose(context.Background(), req)
+	if errors.Is(err, services.ErrEmptySnippet) {
+		fmt.Fprintln(os.Stderr, "snippet is empty; nothing saved")
+		return nil
+	}
+	if err != nil {
+		return sizeError(err, c.Composer.Saver.MaxSize)
+	}
+
+	warnDuplicates(c.Warn, res)
+	warnSecrets(c.Warn, c.Composer.Saver.Secrets)(res.Key, res.Secrets)
+	return writeSaved(c.Output, res, teeOut)
+}
+
+func readerIsTerminal(r io.Reader) bool {
+	type fdReader interface {
+		io.Reader
+		Fd() uintptr
+	}
+	if f, ok := r.(fdReader); ok {
+		return term.IsTerminal(int(f.Fd()))
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/services"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func newTestNewCommand(t *testing.T, write func(path string) error) (*NewCommand, *bytes.Buffer) {
+	t.Helper()
+
+	base := t.TempDir()
+	db, err := storage.InitMetaDB(filepath.Join(base, "meta.db"))
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	var out bytes.Buffer
+	cmd := &NewCommand{
+		Composer: &services.Composer{
+			Saver: &services.Saver{
+				BaseDir: base,
+				DB:      db,
+				Now: func() time.Time {
+					return time.Unix(1_700_000_000, 0)
+				},
+			},
+			Open: func(_ context.Context, path string) error {
+				return write(path)
+			},
+			Templates: filepath.Join(base, "templates"),
+		},
+		Output: &out,
+	}
+	return cmd, &out
+}
+
+func TestNewCommandSavesEditorBuffer(t *testing.T) {
+	cmd, out := newTestNewCommand(t, func(path string) error {
+		return os.WriteFile(path, []byte("hello\n"), 0o600)
+	})
+
+	if err := cmd.Execute([]string{"notes/hello", "@greeting"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "notes/hello" {
+		t.Fatalf("output = %q, want key", got)
+	}
+
+	meta, err := storage.GetMetadata(context.Background(), cmd.Composer.Saver.DB, "notes/hello")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if meta.Tags != "greeting" {
+		t.Fatalf("Tags = %q, want greeting", meta.Tags)
+	}
+}
+
+func TestNewCommandEmptyBufferSavesNothing(t *testing.T) {
+	cmd, out := newTestNewCommand(t, func(string) error { return nil })
+
+	if err := cmd.Execute([]string{"notes/empty"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("output = %q, want none", out.String())
+	}
+	if _, err := storage.GetMetadata(context.Background(), cmd.Composer.Saver.DB, "notes/empty"); err == nil {
+		t.Fatalf("expected no snippet saved")
+	}
+}
+
+func TestNewCommandSeedsFromTemplate(t *testing.T) {
+	var seeded string
+	cmd, _ := newTestNewCommand(t, func(path string) error {
+		data, err := os.ReadFile(path)
+		seeded = string(data)
+		return err
+	})
+
+	templates := cmd.Composer.Templates
+	if err := os.MkdirAll(templates, 0o700); err != nil {
+		t.Fatalf("MkdirAll error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templates, "bug"), []byte("## steps\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	if err := cmd.Execute([]string{"bugs/one", "--from", "bug"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if seeded != "## steps\n" {
+		t.Fatalf("seed = %q, want template", seeded)
+	}
+}
+
+func TestNewCommandSeedsFromSnippet(t *testing.T) {
+	var seeded string
+	cmd, _ := newTestNewCommand(t, func(path string) error {
+		data, err := os.ReadFile(path)
+		seeded = string(data)
+		return err
+	})
+
+	if _, err := cmd.Composer.Saver.Save(context.Background(), services.SaveRequest{
+		Key:    "notes/base",
+		Reader: strings.NewReader("base text\n"),
+	}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	if err := cmd.Execute([]string{"notes/copy", "--from", "notes/base"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if seeded != "base text\n" {
+		t.Fatalf("seed = %q, want snippet", seeded)
+	}
+}
+
+func TestNewCommandRejectsUnknownFrom(t *testing.T) {
+	cmd, _ := newTestNewCommand(t, func(string) error {
+		t.Fatalf("editor should not open")
+		return nil
+	})
+	if err := cmd.Execute([]string{"--from", "missing"}); err == nil {
+		t.Fatalf("expected error for unknown --from")
+	}
+}
+
+func TestNewCommandRejectsExistingKeyBeforeEditing(t *testing.T) {
+	cmd, _ := newTestNewCommand(t, func(string) error {
+		t.Fatalf("editor should not open")
+		return nil
+	})
+	if _, err := cmd.Composer.Saver.Save(context.Background(), services.SaveRequest{
+		Key:    "notes/taken",
+		Reader: strings.NewReader("x"),
+	}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+	if err := cmd.Execute([]string{"notes/taken"}); err == nil {
+		t.Fatalf("expected error for existing key")
+	}
+}
+
+func TestNewCommandEncryptedFailureKeepsNoPlaintext(t *testing.T) {
+	var scratch string
+	cmd, _ := newTestNewCommand(t, func(path string) error {
+		scratch = path
+		return os.WriteFile(path, []byte("hunter2\n"), 0o600)
+	})
+	cmd.Composer.Saver.Passphrase = func() ([]byte, error) { return nil, errors.New("no passphrase given") }
+
+	err := cmd.Execute([]string{"db/prod", "--encrypt"})
+	if err == nil {
+		t.Fatal("Execute succeeded without a passphrase")
+	}
+	if strings.Contains(err.Error(), scratch) {
+		t.Fatalf("error %q points at a kept plaintext copy", err)
+	}
+	if _, err := os.Stat(scratch); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("scratch file still there: %v", err)
+	}
+}
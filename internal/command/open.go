@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/llywelwyn/wow/internal/services"
@@ -12,22 +13,31 @@ import (
 
 type openHandler interface {
 	Open(ctx context.Context, key string, opts services.OpenOptions) error
+	Plan(ctx context.Context, key string, opts services.OpenOptions) (services.OpenPlan, error)
 }
 
 // OpenCommand launches snippets via configured opener or pager.
 type OpenCommand struct {
 	Opener openHandler
+	Output io.Writer
 }
 
 // NewOpenCommand constructs an OpenCommand using defaults from cfg.
 func NewOpenCommand(cfg Config) *OpenCommand {
 	return &OpenCommand{
 		Opener: &services.Opener{
-			BaseDir:   cfg.BaseDir,
-			DB:        cfg.DB,
-			OpenFunc:  cfg.opener(),
-			PagerFunc: cfg.pager(),
+			BaseDir:    cfg.BaseDir,
+			DB:         cfg.DB,
+			OpenFunc:   cfg.opener(),
+			PagerFunc:  cfg.pager(),
+			ViewFunc:   NewViewCommand(cfg).Show,
+			SystemFunc: cfg.System,
+			Rules:      cfg.OpenRules,
+			Vaults:     cfg.vaults(),
+			Hooks:      cfg.Hooks,
+			Passphrase: cfg.passphrase(false),
 		},
+		Output: cfg.writer(),
 	}
 }
 
@@ -43,6 +53,10 @@ func (c *OpenCommand) Execute(args []string) error {
 	fs := flag.NewFlagSet("open", flag.ContinueOnError)
 	fs.SetOutput(os.Stdout)
 	var pager *bool = fs.BoolP("pager", "p", false, "view snippet in pager")
+	var with *string = fs.StringP("with", "w", "", "open with the named rule from config.toml")
+	var raw *bool = fs.BoolP("raw", "r", false, "open Markdown with $WOW_OPENER instead of rendering it")
+	var explain *bool = fs.Bool("explain", false, "print which rule would open the snippet, without opening it")
+	line, search := positionFlags(fs)
 	var help *bool = fs.BoolP("help", "h", false, "display help")
 
 	if err := fs.Parse(args); err != nil {
@@ -51,7 +65,25 @@ func (c *OpenCommand) Execute(args []string) error {
 
 	if *help {
 		fmt.Fprintln(os.Stdout, `Usage:
-  wow open <key> [--pager]`)
+  wow open <key>[:line] [--line n | --search regex] [--pager] [--with <rule>] [--raw] [--explain]
+
+Rules from [[open]] tables in config.toml are tried in order and the
+first match opens the snippet. Markdown no rule matches is rendered
+as by "wow view", unless --raw is given, and binary snippets go to
+the system's opener for their media type; anything else goes to
+$WOW_OPENER. --explain shows which would be used.
+
+  [[open]]
+  name = "images"
+  mime = "image/*"
+  command = "feh"
+
+Rules match by key glob (key), type, lang or media type (mime).
+Their commands, and $WOW_EDITOR, $WOW_OPENER and $WOW_PAGER,
+are split like shell words and may place the snippet with
+{path}, {key}, {line} and {url}. A word whose placeholder is
+empty is dropped. Without {path} or {url}, the file goes last.
+Start a command with "!" to run it with sh -c instead.`)
 		fs.PrintDefaults()
 		return nil
 	}
@@ -61,5 +93,49 @@ func (c *OpenCommand) Execute(args []string) error {
 		return errors.New("open expects exactly one key")
 	}
 
-	return c.Opener.Open(context.Background(), remaining[0], services.OpenOptions{UsePager: *pager})
+	k, at, err := splitPosition(remaining[0], *line, *search)
+	if err != nil {
+		return err
+	}
+
+	opts := services.OpenOptions{UsePager: *pager, With: *with, Raw: *raw, At: at}
+	if *explain {
+		plan, err := c.Opener.Plan(context.Background(), k, opts)
+		if err != nil {
+			return err
+		}
+		return c.printPlan(plan, opts)
+	}
+
+	return c.Opener.Open(context.Background(), k, opts)
+}
+
+func (c *OpenCommand) printPlan(plan services.OpenPlan, opts services.OpenOptions) error {
+	out := c.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	opener := "default opener"
+	switch {
+	case plan.Rule != "":
+		opener = fmt.Sprintf("rule %q: %s", plan.Rule, plan.Command)
+	case opts.UsePager:
+		opener = "pager"
+	case plan.View:
+		opener = "wow view"
+	case plan.System:
+		opener = "system opener"
+	}
+
+	if _, err := fmt.Fprintf(out, "key:    %s\nfile:   %s\nopener: %s\nreason: %s\ntarget: %s\n",
+		plan.Key, plan.Path, opener, plan.Reason, plan.Target); err != nil {
+		return err
+	}
+	if plan.Line > 0 {
+		if _, err := fmt.Fprintf(out, "line:   %d\n", plan.Line); err != nil {
+			return err
+		}
+	}
+	return nil
 }
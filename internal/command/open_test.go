@@ -1,18 +1,22 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/llywelwyn/wow/internal/services"
 )
 
 type stubOpenService struct {
-	called bool
-	key    string
-	opts   services.OpenOptions
-	err    error
+	called  bool
+	planned bool
+	key     string
+	opts    services.OpenOptions
+	plan    services.OpenPlan
+	err     error
 }
 
 func (s *stubOpenService) Open(ctx context.Context, key string, opts services.OpenOptions) error {
@@ -22,6 +26,13 @@ func (s *stubOpenService) Open(ctx context.Context, key string, opts services.Op
 	return s.err
 }
 
+func (s *stubOpenService) Plan(ctx context.Context, key string, opts services.OpenOptions) (services.OpenPlan, error) {
+	s.planned = true
+	s.key = key
+	s.opts = opts
+	return s.plan, s.err
+}
+
 func TestOpenCommandName(t *testing.T) {
 	cmd := &OpenCommand{}
 	if cmd.Name() != "open" {
@@ -82,3 +93,62 @@ func TestOpenCommandPropagatesError(t *testing.T) {
 		t.Fatalf("expected %v, got %v", stub.err, err)
 	}
 }
+
+func TestOpenCommandWithFlag(t *testing.T) {
+	stub := &stubOpenService{}
+	cmd := &OpenCommand{Opener: stub}
+	if err := cmd.Execute([]string{"--with", "glow", "key"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if stub.opts.With != "glow" {
+		t.Fatalf("With = %q, want glow", stub.opts.With)
+	}
+}
+
+func TestOpenCommandRawFlag(t *testing.T) {
+	stub := &stubOpenService{}
+	cmd := &OpenCommand{Opener: stub}
+	if err := cmd.Execute([]string{"-r", "notes/todo.md"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if !stub.opts.Raw {
+		t.Fatalf("expected Raw option")
+	}
+}
+
+func TestOpenCommandExplainPrintsPlan(t *testing.T) {
+	stub := &stubOpenService{plan: services.OpenPlan{
+		Key:     "notes/todo.md",
+		Path:    "/vault/notes/todo.md",
+		Target:  "/vault/notes/todo.md",
+		Rule:    "markdown",
+		Command: "glow {path}",
+		Reason:  `key matches "*.md"`,
+	}}
+	var out bytes.Buffer
+	cmd := &OpenCommand{Opener: stub, Output: &out}
+	if err := cmd.Execute([]string{"--explain", "notes/todo.md"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if stub.called {
+		t.Fatalf("explain should not open the snippet")
+	}
+	if !stub.planned {
+		t.Fatalf("expected Plan to be called")
+	}
+	got := out.String()
+	if !strings.Contains(got, `rule "markdown": glow {path}`) || !strings.Contains(got, `key matches "*.md"`) {
+		t.Fatalf("output = %q", got)
+	}
+}
+
+func TestOpenCommandSearchFlag(t *testing.T) {
+	stub := &stubOpenService{}
+	cmd := &OpenCommand{Opener: stub}
+	if err := cmd.Execute([]string{"ops/deploy", "--search", "^rollback"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if stub.key != "ops/deploy" || stub.opts.At.Search != "^rollback" {
+		t.Fatalf("key = %q, at = %+v", stub.key, stub.opts.At)
+	}
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// passphraseEnv names the variable read for the passphrase before
+// asking on the terminal.
+const passphraseEnv = "WOW_PASSPHRASE"
+
+// ErrNoPassphrase is returned when an encrypted snippet needs a
+// passphrase and there is nowhere to get one from.
+var ErrNoPassphrase = errors.New("a passphrase is needed: set " + passphraseEnv + " or run wow in a terminal")
+
+// passphrase returns where commands get the passphrase for encrypted
+// snippets from: Config.Passphrase when set, else $WOW_PASSPHRASE, else
+// a prompt on the terminal, asked at most once. With confirm, the
+// prompt asks twice, for passphrases about to seal something.
+func (c Config) passphrase(confirm bool) storage.Passphrase {
+	if c.Passphrase != nil {
+		return c.Passphrase
+	}
+	return sync.OnceValues(func() ([]byte, error) {
+		if pass := os.Getenv(passphraseEnv); pass != "" {
+			return []byte(pass), nil
+		}
+		return promptPassphrase(confirm)
+	})
+}
+
+// promptPassphrase reads a passphrase from the controlling terminal,
+// so it works while stdin carries a snippet.
+func promptPassphrase(confirm bool) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, ErrNoPassphrase
+	}
+	defer tty.Close()
+
+	ask := func(prompt string) ([]byte, error) {
+		fmt.Fprint(tty, prompt)
+		pass, err := term.ReadPassword(int(tty.Fd()))
+		fmt.Fprintln(tty)
+		if err != nil {
+			return nil, fmt.Errorf("read passphrase: %w", err)
+		}
+		return pass, nil
+	}
+
+	pass, err := ask("passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := ask("passphrase again: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(pass, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return pass, nil
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PluginPrefix starts the file name of every plugin executable.
+// A plugin named wow-deploy is run as "wow deploy".
+const PluginPrefix = "wow-"
+
+// PluginCommand runs an external plugin executable.
+type PluginCommand struct {
+	name string
+	Path string
+	Env  []string // appended to the current environment.
+}
+
+// Name returns the command keyword.
+func (p *PluginCommand) Name() string { return p.name }
+
+// Execute runs the plugin with args, attached to the current terminal.
+func (p *PluginCommand) Execute(args []string) error {
+	cmd := exec.CommandContext(context.Background(), p.Path, args...)
+	cmd.Env = append(os.Environ(), p.Env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// LookupPlugin searches PluginDirs, in order, for an executable plugin
+// called name. Registered commands are not consulted.
+func (d *Dispatcher) LookupPlugin(name string) (Command, bool) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsRune(name, os.PathSeparator) {
+		return nil, false
+	}
+	for _, dir := range d.PluginDirs {
+		path := filepath.Join(dir, PluginPrefix+name)
+		if isExecutable(path) {
+			return &PluginCommand{name: name, Path: path, Env: d.PluginEnv}, true
+		}
+	}
+	return nil, false
+}
+
+// Plugins returns the names of every plugin found in PluginDirs,
+// skipping any hidden by a registered command or an earlier directory.
+func (d *Dispatcher) Plugins() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range d.PluginDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			if !ok || name == "" || seen[name] {
+				continue
+			}
+			if _, registered := d.registry[name]; registered {
+				continue
+			}
+			if !isExecutable(filepath.Join(dir, entry.Name())) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
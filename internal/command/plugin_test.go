@@ -0,0 +1,84 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, PluginPrefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), mode); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	return path
+}
+
+func TestDispatcherLookupPluginSearchesDirsInOrder(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writePlugin(t, first, "notexec", "exit 0", 0o644)
+	want := writePlugin(t, first, "deploy", "exit 0", 0o755)
+	writePlugin(t, second, "deploy", "exit 1", 0o755)
+
+	d := NewDispatcher()
+	d.PluginDirs = []string{first, second}
+
+	cmd, ok := d.LookupPlugin("deploy")
+	if !ok {
+		t.Fatalf("expected plugin to be found")
+	}
+	if got := cmd.(*PluginCommand).Path; got != want {
+		t.Fatalf("plugin path = %q, want %q", got, want)
+	}
+
+	if _, ok := d.LookupPlugin("notexec"); ok {
+		t.Fatalf("non-executable files should not be plugins")
+	}
+	if _, ok := d.LookupPlugin("missing"); ok {
+		t.Fatalf("expected lookup to fail for missing plugin")
+	}
+}
+
+func TestDispatcherPluginsSkipsRegisteredCommands(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "list", "exit 0", 0o755)
+	writePlugin(t, dir, "deploy", "exit 0", 0o755)
+	writePlugin(t, dir, "audit", "exit 0", 0o755)
+
+	d := NewDispatcher()
+	d.Register(&stubCommand{name: "list"})
+	d.PluginDirs = []string{dir, filepath.Join(dir, "missing")}
+
+	got := d.Plugins()
+	if strings.Join(got, ",") != "audit,deploy" {
+		t.Fatalf("Plugins = %q, want [audit deploy]", got)
+	}
+}
+
+func TestPluginCommandPassesArgsAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	writePlugin(t, dir, "echo", `printf '%s %s' "$WOW_VAULT" "$1" > "`+out+`"`, 0o755)
+
+	d := NewDispatcher()
+	d.PluginDirs = []string{dir}
+	d.PluginEnv = []string{"WOW_VAULT=project"}
+
+	cmd, ok := d.LookupPlugin("echo")
+	if !ok {
+		t.Fatalf("expected plugin to be found")
+	}
+	if err := cmd.Execute([]string{"hello"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if string(got) != "project hello" {
+		t.Fatalf("plugin saw %q, want %q", got, "project hello")
+	}
+}
@@ -0,0 +1,39 @@
+package command
+
+import (
+	"errors"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+// positionFlags registers --line and --search on fs.
+func positionFlags(fs *flag.FlagSet) (*int, *string) {
+	line := fs.IntP("line", "l", 0, "open at this line; also written as <key>:<line>")
+	search := fs.StringP("search", "s", "", "open at the first line matching this regular expression")
+	return line, search
+}
+
+// splitPosition separates a "key:line" argument and merges it with the
+// --line and --search flags.
+func splitPosition(arg string, line int, search string) (string, services.Position, error) {
+	k, suffix, err := key.SplitLine(arg)
+	if err != nil {
+		return "", services.Position{}, err
+	}
+	if line < 0 {
+		return "", services.Position{}, errors.New("--line must be positive")
+	}
+	if suffix > 0 && line > 0 && suffix != line {
+		return "", services.Position{}, errors.New("line given twice")
+	}
+	if suffix > 0 {
+		line = suffix
+	}
+	if line > 0 && search != "" {
+		return "", services.Position{}, errors.New("a line and --search are mutually exclusive")
+	}
+	return k, services.Position{Line: line, Search: search}, nil
+}
@@ -0,0 +1,44 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+func TestSplitPosition(t *testing.T) {
+	tests := []struct {
+		arg    string
+		line   int
+		search string
+		key    string
+		want   services.Position
+	}{
+		{"ops/deploy", 0, "", "ops/deploy", services.Position{}},
+		{"ops/deploy:120", 0, "", "ops/deploy", services.Position{Line: 120}},
+		{"ops/deploy", 120, "", "ops/deploy", services.Position{Line: 120}},
+		{"ops/deploy:120", 120, "", "ops/deploy", services.Position{Line: 120}},
+		{"ops/deploy", 0, "^rollback", "ops/deploy", services.Position{Search: "^rollback"}},
+	}
+	for _, tc := range tests {
+		k, at, err := splitPosition(tc.arg, tc.line, tc.search)
+		if err != nil {
+			t.Fatalf("splitPosition(%q) error = %v", tc.arg, err)
+		}
+		if k != tc.key || at != tc.want {
+			t.Fatalf("splitPosition(%q) = %q, %+v, want %q, %+v", tc.arg, k, at, tc.key, tc.want)
+		}
+	}
+}
+
+func TestSplitPositionRejectsConflicts(t *testing.T) {
+	if _, _, err := splitPosition("ops/deploy:12", 13, ""); err == nil {
+		t.Fatalf("expected error for two different lines")
+	}
+	if _, _, err := splitPosition("ops/deploy:12", 0, "x"); err == nil {
+		t.Fatalf("expected error for line and search")
+	}
+	if _, _, err := splitPosition("ops/deploy", -1, ""); err == nil {
+		t.Fatalf("expected error for negative line")
+	}
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+// redactUsage describes the --redact flag of get, view and list.
+const redactUsage = "mask secrets, for sharing your screen; piped output is only masked when this is given"
+
+// secretScanner returns the scanner to look for secrets with. Its rules
+// apply even when saving does not scan.
+func (c Config) secretScanner() *services.SecretScanner {
+	return orDefaultRules(c.Secrets)
+}
+
+// orDefaultRules returns s, or a scanner with the built-in rules when s
+// is nil.
+func orDefaultRules(s *services.SecretScanner) *services.SecretScanner {
+	if s != nil {
+		return s
+	}
+	return &services.SecretScanner{Rules: services.DefaultSecretRules}
+}
+
+// redacting reports whether output should be masked. Given --redact
+// decides; otherwise the configured default applies on a terminal only,
+// so what is piped on is never masked unless asked for.
+func redacting(fs *flag.FlagSet, byDefault, tty bool) bool {
+	if fs.Changed("redact") {
+		redact, _ := fs.GetBool("redact")
+		return redact
+	}
+	return byDefault && tty
+}
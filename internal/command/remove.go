@@ -22,6 +22,7 @@ func NewRemoveCommand(cfg Config) *RemoveCommand {
 		Remover: &services.Remover{
 			BaseDir: cfg.BaseDir,
 			DB:      cfg.DB,
+			Hooks:   cfg.Hooks,
 		},
 	}
 }
@@ -37,6 +38,7 @@ func (c *RemoveCommand) Execute(args []string) error {
 
 	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
 	fs.SetOutput(os.Stdout)
+	var force *bool = fs.BoolP("force", "f", false, "remove the snippet even if it is protected")
 	var help *bool = fs.BoolP("help", "h", false, "display help")
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -44,7 +46,9 @@ func (c *RemoveCommand) Execute(args []string) error {
 
 	if *help {
 		fmt.Fprintln(os.Stdout, `Usage:
-  wow remove <key>`)
+  wow remove <key> [--force]
+
+  Protected snippets are only removed with --force.`)
 		fs.PrintDefaults()
 		return nil
 	}
@@ -54,5 +58,7 @@ func (c *RemoveCommand) Execute(args []string) error {
 		return errors.New("key required")
 	}
 	key := remaining[0]
-	return c.Remover.Remove(context.Background(), key)
+	remover := *c.Remover
+	remover.Force = *force
+	return remover.Remove(context.Background(), key)
 }
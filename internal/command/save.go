@@ -1,12 +1,15 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	flag "github.com/spf13/pflag"
 	"io"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/llywelwyn/wow/internal/services"
 )
@@ -16,18 +19,29 @@ type SaveCommand struct {
 	Saver  *services.Saver
 	Input  io.Reader
 	Output io.Writer
+	New    *NewCommand // takes over when Input is a terminal.
+	Status io.Writer   // shows the progress of long saves; nil shows none.
+	Warn   io.Writer   // where warnings go, such as duplicate content; nil drops them.
 }
 
 // NewSaveCommand constructs a SaveCommand using default dependencies from cfg.
 func NewSaveCommand(cfg Config) *SaveCommand {
 	return &SaveCommand{
 		Saver: &services.Saver{
-			BaseDir: cfg.BaseDir,
-			DB:      cfg.DB,
-			Now:     cfg.clock(),
+			BaseDir:       cfg.BaseDir,
+			DB:            cfg.DB,
+			Now:           cfg.clock(),
+			Hooks:         cfg.Hooks,
+			MaxSize:       cfg.MaxSnippetSize,
+			CompressAbove: cfg.CompressAbove,
+			Passphrase:    cfg.passphrase(true),
+			Secrets:       cfg.Secrets,
 		},
 		Input:  cfg.reader(),
 		Output: cfg.writer(),
+		New:    NewNewCommand(cfg),
+		Status: cfg.status(),
+		Warn:   os.Stderr,
 	}
 }
 
@@ -42,6 +56,7 @@ func (c *SaveCommand) Execute(args []string) error {
 		return errors.New("save command not fully configured")
 	}
 
+	rawArgs := args
 	tagArgs := extractTagArgs(args)
 	args = tagArgs.Others
 
@@ -50,6 +65,9 @@ func (c *SaveCommand) Execute(args []string) error {
 	var tee *bool = fs.BoolP("tee", "T", false, "print stdin back out, rather than the key")
 	var desc *string = fs.StringP("desc", "d", "", "description")
 	var tags *string = fs.StringP("tag", "t", "", "comma-separated tags, e.g. one,two")
+	var noDupes *bool = fs.Bool("no-dupes", false, "refuse content already saved under another key")
+	var encrypt *bool = fs.BoolP("encrypt", "e", false, "encrypt the snippet with a passphrase")
+	var noScan *bool = fs.Bool("no-scan", false, "save without scanning for secrets")
 	var help *bool = fs.BoolP("help", "h", false, "display help")
 
 	var keyArg string
@@ -64,29 +82,168 @@ func (c *SaveCommand) Execute(args []string) error {
 
 	if *help {
 		fmt.Fprintln(c.Output, `Usage:
-  wow save [key] [--desc description] [--tag tags] [@tag ...] < snippet`)
+  wow save [key] [--desc description] [--tag tags] [@tag ...] < snippet
+
+Without piped input, opens your editor like "wow new".
+Saving content already saved under another key warns,
+or fails with --no-dupes.
+Input over max_snippet_size in config.toml is refused, and
+input of at least compress_above is stored gzipped. Set them
+at the top of config.toml, before any [table]:
+
+  max_snippet_size = "10MB"
+  compress_above = "64KB"
+
+Content that looks like it holds secrets is tagged @secret,
+refused or encrypted, as [secrets] in config.toml says;
+pass --no-scan to skip the check. See "wow scan -h".
+With --encrypt, only AES-GCM ciphertext is written to disk;
+the passphrase comes from $WOW_PASSPHRASE or is asked for.
+
+Executables in $WOW_HOME/hooks run around snippet changes:
+pre-save, post-save, pre-edit, post-edit, pre-remove,
+post-remove and post-open. A failing pre- hook cancels the
+change; pre-save hooks can read the new content from
+$WOW_FILE to gate it. Put --no-hooks before any command to
+skip them, e.g. "wow --no-hooks save note".`)
 		fs.PrintDefaults()
 		return nil
 	}
 
+	if c.New != nil && readerIsTerminal(c.Input) {
+		return c.New.Execute(rawArgs)
+	}
+
 	addTags := append(splitTags(*tags), tagArgs.Add...)
 
-	res, err := c.Saver.Save(context.Background(), services.SaveRequest{
+	req := services.SaveRequest{
 		Key:         keyArg,
 		Description: *desc,
 		Tags:        addTags,
 		Reader:      c.Input,
-	})
+		NoDupes:     *noDupes,
+		Encrypt:     *encrypt,
+		SkipScan:    *noScan,
+	}
+	var teeOut *teeWriter
+	if *tee {
+		teeOut = newTeeWriter(c.Output)
+		req.Tee = teeOut
+	}
+	if c.Status != nil {
+		progress := &progressReporter{w: c.Status}
+		defer progress.done()
+		req.Progress = progress.update
+	}
+
+	res, err := c.Saver.Save(context.Background(), req)
 	if err != nil {
-		return err
+		return sizeError(err, c.Saver.MaxSize)
 	}
 
-	output := res.Key
-	if *tee {
-		output = string(res.Contents[:])
+	warnDuplicates(c.Warn, res)
+	warnSecrets(c.Warn, c.Saver.Secrets)(res.Key, res.Secrets)
+	return writeSaved(c.Output, res, teeOut)
+}
+
+// writeSaved reports a saved snippet by its key, unless tee already
+// passed its content through.
+func writeSaved(w io.Writer, res services.SaveResult, tee *teeWriter) error {
+	if tee != nil && !tee.withheld {
+		return nil
 	}
-	if _, err := fmt.Fprintln(c.Output, output); err != nil {
+	if _, err := fmt.Fprintln(w, res.Key); err != nil {
 		return fmt.Errorf("write key to output: %w", err)
 	}
 	return nil
 }
+
+// warnDuplicates points out other keys holding the content just saved.
+func warnDuplicates(w io.Writer, res services.SaveResult) {
+	if w == nil || len(res.Duplicates) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "warning: %q has the same content as %s; see \"wow dupes\"\n",
+		res.Key, strings.Join(quoted(res.Duplicates), ", "))
+}
+
+// warnSecrets returns a function pointing out secrets found in a
+// snippet, and what the policy of scanner did about them.
+func warnSecrets(w io.Writer, scanner *services.SecretScanner) func(key string, findings []services.SecretFinding) {
+	return func(key string, findings []services.SecretFinding) {
+		if w == nil || scanner == nil || len(findings) == 0 {
+			return
+		}
+		done := "tagged @" + services.SecretTag
+		if scanner.Policy == services.SecretsEncrypt {
+			done = "saved it encrypted"
+		}
+		fmt.Fprintf(w, "warning: %q looks like it holds secrets (%s); %s\n", key, services.DescribeSecrets(findings), done)
+	}
+}
+
+func quoted(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = fmt.Sprintf("%q", v)
+	}
+	return out
+}
+
+// sizeError names the configured limit when a snippet is too large.
+func sizeError(err error, limit int64) error {
+	if errors.Is(err, services.ErrSnippetTooLarge) {
+		return fmt.Errorf("%w: over the %s max_snippet_size in config.toml", services.ErrSnippetTooLarge, formatSize(limit))
+	}
+	return err
+}
+
+// teeWriter passes a snippet through to w as it is saved, for --tee.
+// A terminal is spared binary content: a NUL byte near the start
+// withholds all of it, and the key is written instead.
+type teeWriter struct {
+	w        io.Writer
+	terminal bool
+	started  bool
+	withheld bool
+}
+
+func newTeeWriter(w io.Writer) *teeWriter {
+	return &teeWriter{w: w, terminal: writerIsTerminal(w)}
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	if !t.started {
+		t.started = true
+		t.withheld = t.terminal && bytes.IndexByte(p[:min(len(p), 512)], 0) >= 0
+	}
+	if t.withheld {
+		return len(p), nil
+	}
+	return t.w.Write(p)
+}
+
+// progressReporter shows how much of a long save has been read.
+type progressReporter struct {
+	w     io.Writer
+	last  time.Time
+	shown bool
+}
+
+// update is called as content is read. Saves under a megabyte finish
+// too quickly to be worth a line.
+func (p *progressReporter) update(read int64) {
+	if read < 1<<20 || time.Since(p.last) < 200*time.Millisecond {
+		return
+	}
+	p.last = time.Now()
+	p.shown = true
+	fmt.Fprintf(p.w, "\rsaving %s", formatSize(read))
+}
+
+// done clears the progress line, if one was shown.
+func (p *progressReporter) done() {
+	if p.shown {
+		fmt.Fprint(p.w, "\r\x1b[K")
+	}
+}
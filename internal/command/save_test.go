@@ -3,6 +3,7 @@ package command
 import (
 	"bytes"
 	"context"
+	"errors"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -123,3 +124,61 @@ func TestSaveCommandImplicitTags(t *testing.T) {
 		t.Fatalf("tags stored = %q, want foo,bar", meta.Tags)
 	}
 }
+
+func TestSaveCommandTeeWritesBinaryUnchanged(t *testing.T) {
+	cmd, cleanup := newTestSaveCommand(t)
+	defer cleanup()
+
+	content := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	var out bytes.Buffer
+	cmd.Input = strings.NewReader(content)
+	cmd.Output = &out
+
+	if err := cmd.Execute([]string{"img/logo", "--tee"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if out.String() != content {
+		t.Fatalf("output = %q, want the bytes without a newline", out.String())
+	}
+}
+
+func TestSaveCommandRejectsTooLarge(t *testing.T) {
+	cmd, cleanup := newTestSaveCommand(t)
+	defer cleanup()
+
+	cmd.Saver.MaxSize = 1024
+	cmd.Input = strings.NewReader(strings.Repeat("x", 2048))
+	cmd.Output = &bytes.Buffer{}
+
+	err := cmd.Execute([]string{"big"})
+	if !errors.Is(err, services.ErrSnippetTooLarge) {
+		t.Fatalf("Execute error = %v, want ErrSnippetTooLarge", err)
+	}
+	if !strings.Contains(err.Error(), "1.0 KB max_snippet_size") {
+		t.Fatalf("error = %q, want it to name the limit", err)
+	}
+}
+
+func TestSaveCommandWarnsAboutDuplicates(t *testing.T) {
+	cmd, cleanup := newTestSaveCommand(t)
+	defer cleanup()
+
+	var warn bytes.Buffer
+	cmd.Warn = &warn
+	for _, k := range []string{"a", "b"} {
+		cmd.Input = strings.NewReader("same")
+		cmd.Output = &bytes.Buffer{}
+		if err := cmd.Execute([]string{k}); err != nil {
+			t.Fatalf("Execute(%s) error = %v", k, err)
+		}
+	}
+	if want := "warning: \"b\" has the same content as \"a\"; see \"wow dupes\"\n"; warn.String() != want {
+		t.Fatalf("warning = %q, want %q", warn.String(), want)
+	}
+
+	cmd.Input = strings.NewReader("same")
+	err := cmd.Execute([]string{"c", "--no-dupes"})
+	if !errors.Is(err, services.ErrDuplicateContent) {
+		t.Fatalf("Execute with --no-dupes error = %v, want ErrDuplicateContent", err)
+	}
+}
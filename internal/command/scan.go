@@ -0,0 +1,138 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+// ScanCommand audits saved snippets for secrets.
+type ScanCommand struct {
+	Auditor *services.SecretAuditor
+	Output  io.Writer
+}
+
+// NewScanCommand constructs a ScanCommand using defaults from cfg.
+func NewScanCommand(cfg Config) *ScanCommand {
+	var auditor *services.SecretAuditor
+	if cfg.DB != nil {
+		auditor = &services.SecretAuditor{
+			BaseDir: cfg.BaseDir,
+			DB:      cfg.DB,
+			Scanner: cfg.secretScanner(),
+		}
+	}
+	return &ScanCommand{
+		Auditor: auditor,
+		Output:  cfg.writer(),
+	}
+}
+
+// Name returns the command keyword.
+func (c *ScanCommand) Name() string { return "scan" }
+
+// Execute scans the snippets under an optional key prefix.
+func (c *ScanCommand) Execute(args []string) error {
+	if c.Output == nil || c.Auditor == nil {
+		return errors.New("scan command not fully configured")
+	}
+
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow scan [prefix]
+
+  wow! Looks through saved snippets for private
+  keys, cloud access keys, tokens and passwords,
+  using the rules saves are checked with, and
+  lists those that look like they hold secrets.
+
+  Encrypted snippets are skipped. Saves and edits
+  are checked too: by default such snippets are
+  tagged @secret, and [secrets] in config.toml can
+  block or encrypt them instead, or make --redact
+  the default on a terminal. Extra rules can be
+  added there as well:
+
+    [secrets]
+    policy = "encrypt"  # or "warn", "block", "off"
+    redact = true
+
+    [[secrets.rule]]
+    name = "internal token"
+    pattern = "itk_[0-9a-f]{32}"`)
+		fmt.Fprintln(c.Output)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	remaining := fs.Args()
+	if len(remaining) > 1 {
+		return errors.New("scan takes at most one prefix")
+	}
+	var prefix string
+	if len(remaining) == 1 {
+		prefix = remaining[0]
+	}
+
+	results, err := c.Auditor.Audit(context.Background(), prefix)
+	if err != nil {
+		return err
+	}
+
+	flagged, scanned, unreadable := 0, 0, 0
+	for _, res := range results {
+		switch {
+		case res.Encrypted:
+			continue
+		case res.Err != nil:
+			fmt.Fprintf(c.Output, "%s: %v\n", res.Key, res.Err)
+			unreadable++
+			continue
+		case len(res.Findings) > 0:
+			fmt.Fprintf(c.Output, "%s: %s\n", res.Key, services.DescribeSecrets(res.Findings))
+			flagged++
+		}
+		scanned++
+	}
+
+	// Snippets that could not be read were never checked, so they
+	// fail the scan even when nothing else was found.
+	var unread error
+	if unreadable > 0 {
+		unread = fmt.Errorf("%d %s could not be scanned", unreadable, pluralSnippets(unreadable))
+	}
+	if flagged == 0 {
+		if _, err := fmt.Fprintf(c.Output, "no secrets found in %d %s\n", scanned, pluralSnippets(scanned)); err != nil {
+			return err
+		}
+		return unread
+	}
+	fmt.Fprintln(c.Output)
+	fmt.Fprintln(c.Output, `Run "wow encrypt <key>" to encrypt them.`)
+	err = fmt.Errorf("%d of %d snippets look like they hold secrets", flagged, scanned)
+	if unread != nil {
+		err = fmt.Errorf("%w; %w", err, unread)
+	}
+	return err
+}
+
+// pluralSnippets returns "snippet" or "snippets" to follow n.
+func pluralSnippets(n int) string {
+	if n == 1 {
+		return "snippet"
+	}
+	return "snippets"
+}
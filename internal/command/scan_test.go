@@ -0,0 +1,37 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+func TestScanCommandFailsOnUnreadableSnippets(t *testing.T) {
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+
+	for _, key := range []string{"notes", "k8s/pods"} {
+		req := services.SaveRequest{Key: key, Reader: strings.NewReader("kubectl get " + key + "\n")}
+		if _, err := saver.Save(context.Background(), req); err != nil {
+			t.Fatalf("Save(%s) error = %v", key, err)
+		}
+	}
+	if err := os.Remove(filepath.Join(cfg.BaseDir, "k8s", "pods")); err != nil {
+		t.Fatalf("Remove error = %v", err)
+	}
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	err := NewScanCommand(cfg).Execute(nil)
+	if err == nil || err.Error() != "1 snippet could not be scanned" {
+		t.Fatalf("Execute error = %v, want the unreadable snippet counted", err)
+	}
+	if !strings.HasSuffix(out.String(), "no secrets found in 1 snippet\n") {
+		t.Fatalf("output = %q", out.String())
+	}
+}
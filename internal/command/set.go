@@ -0,0 +1,124 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/services"
+	"github.com/llywelwyn/wow/internal/ui"
+	"github.com/llywelwyn/wow/internal/vault"
+)
+
+// SetCommand overrides metadata wow works out for itself, such as a
+// snippet's type.
+type SetCommand struct {
+	BaseDir string
+	Output  io.Writer
+	Meta    *services.Metadata
+	Vaults  vault.Stack
+}
+
+// NewSetCommand constructs a SetCommand using defaults from cfg.
+func NewSetCommand(cfg Config) *SetCommand {
+	var meta *services.Metadata
+	if cfg.DB != nil {
+		meta = &services.Metadata{
+			DB:  cfg.DB,
+			Now: cfg.clock(),
+		}
+	}
+	return &SetCommand{
+		BaseDir: cfg.BaseDir,
+		Output:  cfg.writer(),
+		Meta:    meta,
+		Vaults:  cfg.vaults(),
+	}
+}
+
+// Name returns the command keyword.
+func (c *SetCommand) Name() string { return "set" }
+
+// Execute updates the metadata of the snippet identified by key.
+func (c *SetCommand) Execute(args []string) error {
+	if c.Output == nil || c.Meta == nil {
+		return errors.New("set command not fully configured")
+	}
+
+	fs := flag.NewFlagSet("set", flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var typ *string = fs.StringP("type", "T", "", "set the snippet type, e.g. code, script, json or text")
+	var lang *string = fs.StringP("lang", "l", "", "set the snippet language, e.g. go or bash")
+	var force *bool = fs.BoolP("force", "f", false, "change the snippet even if it is protected")
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow set <key> [--type type] [--lang language] [--force]
+
+  wow! Overrides the type and language it detected
+  when the snippet was saved. They choose the icon
+  in "wow list", highlighting in "wow get", and the
+  [[open]] rules that match.
+
+  Some examples:
+    wow set notes/deploy --type script --lang bash
+    wow set query --type sql
+
+  Protected snippets are only changed with --force.`)
+		fmt.Fprintln(c.Output)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	remaining := fs.Args()
+	if len(remaining) != 1 {
+		return errors.New("set expects exactly one key")
+	}
+	if *typ == "" && *lang == "" {
+		return errors.New("nothing to set; pass --type or --lang")
+	}
+
+	found, err := c.resolve(remaining[0])
+	if err != nil {
+		return err
+	}
+	if found.ReadOnly {
+		return fmt.Errorf("%w: %q is in %q", vault.ErrReadOnly, remaining[0], found.Name)
+	}
+
+	// Write to the vault the snippet was found in, not only the primary.
+	svc := *c.Meta
+	svc.Force = *force
+	if found.DB != nil {
+		svc.DB = found.DB
+	}
+	meta, err := svc.SetType(context.Background(), remaining[0], *typ, *lang)
+	if err != nil {
+		return err
+	}
+
+	styles := ui.DefaultStyles()
+	_, err = fmt.Fprintf(c.Output, "%s %s %s\n",
+		styles.Icon.Render(meta.TypeIcon()), styles.Key.Render(meta.Key), styles.Subtle.Render(meta.TypeLabel()))
+	return err
+}
+
+// resolve finds the vault holding the snippet.
+// Without configured vaults it falls back to BaseDir alone.
+func (c *SetCommand) resolve(rawKey string) (vault.Vault, error) {
+	if len(c.Vaults) > 0 {
+		found, _, err := c.Vaults.Resolve(rawKey)
+		return found, err
+	}
+	_, err := key.ResolvePath(c.BaseDir, rawKey)
+	return vault.Vault{Name: "user", BaseDir: c.BaseDir}, err
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/services"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func TestSetCommandOverridesType(t *testing.T) {
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+
+	if _, err := saver.Save(context.Background(), services.SaveRequest{Key: "ops/deploy", Reader: strings.NewReader("kubectl apply -f .\n")}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	if err := NewSetCommand(cfg).Execute([]string{"ops/deploy", "--type", "script", "--lang", "bash"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if got := out.String(); got != "sh ops/deploy script/bash\n" {
+		t.Fatalf("output = %q", got)
+	}
+
+	meta, err := storage.GetMetadata(context.Background(), cfg.DB, "ops/deploy")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if meta.Type != "script" || meta.Language != "bash" {
+		t.Fatalf("meta = %+v", meta)
+	}
+}
+
+func TestSetCommandRequiresSomethingToSet(t *testing.T) {
+	cfg, _, cleanup := setupGetTest(t)
+	defer cleanup()
+
+	cfg.Output = &bytes.Buffer{}
+	if err := NewSetCommand(cfg).Execute([]string{"ops/deploy"}); err == nil {
+		t.Fatalf("expected error without --type or --lang")
+	}
+	if err := NewSetCommand(cfg).Execute([]string{"--type", "code"}); err == nil {
+		t.Fatalf("expected error without a key")
+	}
+}
@@ -0,0 +1,161 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/storage"
+	"github.com/llywelwyn/wow/internal/ui"
+	"github.com/llywelwyn/wow/internal/vault"
+)
+
+// VaultsCommand prints the vault search path and any shadowed keys.
+type VaultsCommand struct {
+	Vaults vault.Stack
+	Output io.Writer
+}
+
+// NewVaultsCommand constructs a VaultsCommand using defaults from cfg.
+func NewVaultsCommand(cfg Config) *VaultsCommand {
+	return &VaultsCommand{
+		Vaults: cfg.vaults(),
+		Output: cfg.writer(),
+	}
+}
+
+// Name returns the command keyword.
+func (c *VaultsCommand) Name() string { return "vaults" }
+
+// Execute lists vaults in precedence order, followed by shadowed keys.
+func (c *VaultsCommand) Execute(args []string) error {
+	if len(c.Vaults) == 0 || c.Output == nil {
+		return errors.New("vaults command not fully configured")
+	}
+
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var plain *string = fs.String("plain", "", "removes pretty formatting; pass a string to override tab-delimiter")
+	fs.Lookup("plain").NoOptDefVal = "\t"
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow vaults [--plain]
+
+  wow! Shows the vaults it searches, highest precedence first.
+  Reads take the first vault holding a key, and writes always
+  go to the one read-write vault.
+
+  Inside a project (any directory under one holding a .wow/
+  made by "wow init") the project vault comes first and takes
+  writes. Run "wow --global <command>" to use your user vault.
+
+  Extra read-only vaults are layered in with $WOW_VAULTS,
+  a list of directories separated like $PATH.
+
+  Keys present in more than one vault are listed afterwards,
+  along with the vaults whose copy they hide.`)
+		fmt.Fprintln(c.Output)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	ctx := context.Background()
+	shadows, err := c.Vaults.Shadowed(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *plain != "" || !writerIsTerminal(c.Output) {
+		delimiter := *plain
+		if delimiter == "" {
+			delimiter = "\t"
+		}
+		return c.renderPlain(ctx, shadows, delimiter)
+	}
+	return c.renderStyled(ctx, shadows)
+}
+
+func (c *VaultsCommand) renderPlain(ctx context.Context, shadows []vault.Shadow, delimiter string) error {
+	for _, v := range c.Vaults {
+		count, err := countSnippets(ctx, v)
+		if err != nil {
+			return err
+		}
+		fields := []string{v.Name, vaultMode(v), fmt.Sprint(count), v.BaseDir}
+		if _, err := fmt.Fprintln(c.Output, strings.Join(fields, delimiter)); err != nil {
+			return err
+		}
+	}
+	for _, s := range shadows {
+		fields := []string{"shadowed", s.Key, s.Winner, strings.Join(s.Hidden, ",")}
+		if _, err := fmt.Fprintln(c.Output, strings.Join(fields, delimiter)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *VaultsCommand) renderStyled(ctx context.Context, shadows []vault.Shadow) error {
+	styles := ui.DefaultStyles()
+
+	for i, v := range c.Vaults {
+		count, err := countSnippets(ctx, v)
+		if err != nil {
+			return err
+		}
+		line := fmt.Sprintf("%s %s %s %s",
+			styles.Subtle.Render(fmt.Sprintf("%d.", i+1)),
+			styles.Key.Render(v.Name),
+			styles.Secondary.Render(fmt.Sprintf("%s, %d snippets", vaultMode(v), count)),
+			styles.Subtle.Render(v.BaseDir),
+		)
+		if _, err := fmt.Fprintln(c.Output, line); err != nil {
+			return err
+		}
+	}
+
+	if len(shadows) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(c.Output)
+	fmt.Fprintln(c.Output, styles.Header.Render("shadowed keys"))
+	for _, s := range shadows {
+		line := fmt.Sprintf("  %s %s %s",
+			styles.Key.Render(s.Key),
+			styles.Positive.Render(s.Winner),
+			styles.Subtle.Render("hides "+strings.Join(s.Hidden, ", ")),
+		)
+		if _, err := fmt.Fprintln(c.Output, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func vaultMode(v vault.Vault) string {
+	if v.ReadOnly {
+		return "read-only"
+	}
+	return "read-write"
+}
+
+func countSnippets(ctx context.Context, v vault.Vault) (int, error) {
+	if v.DB == nil {
+		return 0, nil
+	}
+	entries, err := storage.ListMetadata(ctx, v.DB)
+	if err != nil {
+		return 0, fmt.Errorf("vault %q: %w", v.Name, err)
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/config"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/storage"
+	"github.com/llywelwyn/wow/internal/vault"
+)
+
+// newLayeredConfig returns a Config searching a writable user vault
+// followed by a read-only team vault.
+func newLayeredConfig(t *testing.T, user, team map[string]string) Config {
+	t.Helper()
+
+	seed := func(snippets map[string]string) string {
+		dir := t.TempDir()
+		db, err := storage.InitMetaDB(filepath.Join(dir, "meta.db"))
+		if err != nil {
+			t.Fatalf("InitMetaDB error = %v", err)
+		}
+		defer db.Close()
+
+		now := time.Unix(1_700_000_000, 0)
+		for k, content := range snippets {
+			if err := storage.Save(filepath.Join(dir, filepath.FromSlash(k)), strings.NewReader(content)); err != nil {
+				t.Fatalf("Save error = %v", err)
+			}
+			meta := model.Metadata{Key: k, Type: "text", Created: now, Modified: now}
+			if err := storage.InsertMetadata(context.Background(), db, meta); err != nil {
+				t.Fatalf("InsertMetadata error = %v", err)
+			}
+		}
+		return dir
+	}
+
+	userDir, teamDir := seed(user), seed(team)
+	stack, err := vault.Open([]config.Vault{
+		{Name: "user", BaseDir: userDir, MetaDB: filepath.Join(userDir, "meta.db")},
+		{Name: "team", BaseDir: teamDir, MetaDB: filepath.Join(teamDir, "meta.db"), ReadOnly: true},
+	})
+	if err != nil {
+		t.Fatalf("vault.Open error = %v", err)
+	}
+	t.Cleanup(func() { _ = stack.Close() })
+
+	return Config{
+		BaseDir: userDir,
+		DB:      stack.Primary().DB,
+		Clock: func() time.Time {
+			return time.Unix(1_700_000_100, 0)
+		},
+		Vaults: stack,
+	}
+}
+
+func TestGetCommandReadsFromSharedVault(t *testing.T) {
+	cfg := newLayeredConfig(t,
+		map[string]string{"ops/deploy": "mine"},
+		map[string]string{"ops/deploy": "theirs", "ops/rollback": "team only"},
+	)
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	getCmd := NewGetCommand(cfg)
+
+	if err := getCmd.Execute([]string{"ops/deploy"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if out.String() != "mine" {
+		t.Fatalf("output = %q, want primary vault content", out.String())
+	}
+
+	out.Reset()
+	if err := getCmd.Execute([]string{"ops/rollback"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if out.String() != "team only" {
+		t.Fatalf("output = %q, want shared vault content", out.String())
+	}
+}
+
+func TestGetCommandRefusesTagsInSharedVault(t *testing.T) {
+	cfg := newLayeredConfig(t, nil, map[string]string{"ops/rollback": "team only"})
+
+	cfg.Output = &bytes.Buffer{}
+	getCmd := NewGetCommand(cfg)
+
+	if err := getCmd.Execute([]string{"ops/rollback", "@mine"}); !errors.Is(err, vault.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestListCommandShowsVault(t *testing.T) {
+	cfg := newLayeredConfig(t,
+		map[string]string{"ops/deploy": "mine"},
+		map[string]string{"ops/deploy": "theirs", "ops/rollback": "team only"},
+	)
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	listCmd := NewListCommand(cfg)
+
+	if err := listCmd.Execute([]string{"--vault"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{"ops/deploy\tuser", "ops/rollback\tteam"}
+	if len(got) != len(want) {
+		t.Fatalf("lines = %q, want %q", got, want)
+	}
+	for _, line := range want {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("output %q missing %q", out.String(), line)
+		}
+	}
+}
+
+func TestVaultsCommandShowsLayeringAndShadows(t *testing.T) {
+	cfg := newLayeredConfig(t,
+		map[string]string{"ops/deploy": "mine"},
+		map[string]string{"ops/deploy": "theirs", "ops/rollback": "team only"},
+	)
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	vaultsCmd := NewVaultsCommand(cfg)
+
+	if err := vaultsCmd.Execute(nil); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "user\tread-write\t1\t") {
+		t.Fatalf("line 0 = %q, want user vault first", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "team\tread-only\t2\t") {
+		t.Fatalf("line 1 = %q, want team vault second", lines[1])
+	}
+	if lines[2] != "shadowed\tops/deploy\tuser\tteam" {
+		t.Fatalf("line 2 = %q, want shadowed ops/deploy", lines[2])
+	}
+}
@@ -0,0 +1,153 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+// rootNamespace names the group of keys without a "/" in summaries.
+const rootNamespace = "(root)"
+
+// VerifyCommand checks snippet files against their recorded hashes.
+type VerifyCommand struct {
+	Verifier *services.Verifier
+	Output   io.Writer
+}
+
+// NewVerifyCommand constructs a VerifyCommand using defaults from cfg.
+func NewVerifyCommand(cfg Config) *VerifyCommand {
+	var verifier *services.Verifier
+	if cfg.DB != nil {
+		verifier = &services.Verifier{
+			BaseDir: cfg.BaseDir,
+			DB:      cfg.DB,
+			Now:     cfg.clock(),
+		}
+	}
+	return &VerifyCommand{
+		Verifier: verifier,
+		Output:   cfg.writer(),
+	}
+}
+
+// Name returns the command keyword.
+func (c *VerifyCommand) Name() string { return "verify" }
+
+// Execute verifies the snippets under an optional key prefix.
+func (c *VerifyCommand) Execute(args []string) error {
+	if c.Output == nil || c.Verifier == nil {
+		return errors.New("verify command not fully configured")
+	}
+
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var accept *bool = fs.BoolP("accept", "a", false, "adopt files changed outside wow as the snippets' content")
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow verify [prefix] [--accept]
+
+  wow! Checks every snippet file against the hash
+  recorded when it was saved, and reports those
+  modified outside wow, truncated, unreadable or
+  missing, with a summary for each namespace.
+
+  Pass --accept to keep files that were changed
+  as the snippets' new content.
+
+  Some examples:
+    wow verify
+    wow verify k8s/ --accept`)
+		fmt.Fprintln(c.Output)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	remaining := fs.Args()
+	if len(remaining) > 1 {
+		return errors.New("verify takes at most one prefix")
+	}
+	var prefix string
+	if len(remaining) == 1 {
+		prefix = remaining[0]
+	}
+
+	results, err := c.Verifier.Verify(context.Background(), prefix, *accept)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		_, err := fmt.Fprintf(c.Output, "no snippets under %q\n", prefix)
+		return err
+	}
+
+	failed, reported := 0, 0
+	for _, res := range results {
+		if res.Integrity == services.IntegrityOK {
+			continue
+		}
+		line := fmt.Sprintf("%-10s %s", res.Integrity, res.Key)
+		switch {
+		case res.Accepted:
+			line += " (accepted)"
+		case res.Err != nil:
+			line += ": " + res.Err.Error()
+		}
+		fmt.Fprintln(c.Output, line)
+		reported++
+		if !res.Accepted {
+			failed++
+		}
+	}
+	if reported > 0 {
+		fmt.Fprintln(c.Output)
+	}
+	writeVerifySummary(c.Output, results)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d snippets failed verification", failed, len(results))
+	}
+	return nil
+}
+
+// writeVerifySummary counts the results of each namespace, the first
+// segment of their keys.
+func writeVerifySummary(w io.Writer, results []services.VerifyResult) {
+	var namespaces []string
+	counts := make(map[string]map[services.Integrity]int)
+	width := 0
+	for _, res := range results {
+		ns, _, found := strings.Cut(res.Key, "/")
+		if !found {
+			ns = rootNamespace
+		}
+		if counts[ns] == nil {
+			counts[ns] = make(map[services.Integrity]int)
+			namespaces = append(namespaces, ns)
+			width = max(width, len(ns))
+		}
+		counts[ns][res.Integrity]++
+	}
+
+	for _, ns := range namespaces {
+		var parts []string
+		for integrity := services.IntegrityOK; integrity <= services.IntegrityUnrecorded; integrity++ {
+			if n := counts[ns][integrity]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", n, integrity))
+			}
+		}
+		fmt.Fprintf(w, "%-*s  %s\n", width, ns, strings.Join(parts, ", "))
+	}
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+func TestVerifyCommandReportsAndAccepts(t *testing.T) {
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+
+	for _, key := range []string{"k8s/pods", "k8s/nodes", "notes"} {
+		req := services.SaveRequest{Key: key, Reader: strings.NewReader("kubectl get " + key + "\n")}
+		if _, err := saver.Save(context.Background(), req); err != nil {
+			t.Fatalf("Save(%s) error = %v", key, err)
+		}
+	}
+	path := filepath.Join(cfg.BaseDir, "k8s", "pods")
+	if err := os.WriteFile(path, []byte("kubectl get pods -A --watch\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	err := NewVerifyCommand(cfg).Execute(nil)
+	if err == nil || err.Error() != "1 of 3 snippets failed verification" {
+		t.Fatalf("Execute error = %v, want a failure count", err)
+	}
+	want := `modified   k8s/pods
+
+k8s     1 ok, 1 modified
+(root)  1 ok
+`
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := NewVerifyCommand(cfg).Execute([]string{"k8s/", "--accept"}); err != nil {
+		t.Fatalf("Execute --accept error = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "modified   k8s/pods (accepted)\n") {
+		t.Fatalf("output = %q, want the change accepted", out.String())
+	}
+
+	out.Reset()
+	if err := NewVerifyCommand(cfg).Execute(nil); err != nil {
+		t.Fatalf("Execute after accept error = %v", err)
+	}
+	if out.String() != "k8s     2 ok\n(root)  1 ok\n" {
+		t.Fatalf("output = %q, want everything ok", out.String())
+	}
+}
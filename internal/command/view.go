@@ -0,0 +1,175 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/runner"
+	"github.com/llywelwyn/wow/internal/services"
+	"github.com/llywelwyn/wow/internal/storage"
+	"github.com/llywelwyn/wow/internal/ui"
+	"github.com/llywelwyn/wow/internal/vault"
+)
+
+// defaultViewWidth is used when Output is not a terminal.
+const defaultViewWidth = 80
+
+// ViewCommand renders Markdown snippets in the terminal.
+//
+// Documents taller than the screen are piped through Pager, like get does.
+type ViewCommand struct {
+	BaseDir string
+	Output  io.Writer
+	Vaults  vault.Stack
+	Pager   func(context.Context, io.Reader) error // nil never pages.
+	Screen  func() (width, height int, ok bool)    // defaults to the size of Output; ok when it is a terminal.
+
+	Passphrase storage.Passphrase      // for encrypted snippets.
+	Secrets    *services.SecretScanner // what --redact masks; defaults to the built-in rules.
+	Redact     bool                    // masks secrets on a terminal without --redact.
+}
+
+// NewViewCommand constructs a ViewCommand using defaults from cfg.
+func NewViewCommand(cfg Config) *ViewCommand {
+	return &ViewCommand{
+		BaseDir: cfg.BaseDir,
+		Output:  cfg.writer(),
+		Vaults:  cfg.vaults(),
+		Pager:   cfg.PageView,
+
+		Passphrase: cfg.passphrase(false),
+		Secrets:    cfg.secretScanner(),
+		Redact:     cfg.Redact,
+	}
+}
+
+// Name returns the command keyword.
+func (c *ViewCommand) Name() string { return "view" }
+
+// Execute renders the snippet identified by key.
+func (c *ViewCommand) Execute(args []string) error {
+	if c.Output == nil || c.BaseDir == "" {
+		return errors.New("view command not fully configured")
+	}
+
+	fs := flag.NewFlagSet("view", flag.ContinueOnError)
+	fs.SetOutput(c.Output)
+	var raw *bool = fs.BoolP("raw", "r", false, "print the Markdown source without rendering it")
+	var noPager *bool = fs.Bool("no-pager", false, "never page output, even on a terminal")
+	fs.Bool("redact", false, redactUsage)
+	var help *bool = fs.BoolP("help", "h", false, "display help")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *help {
+		fmt.Fprintln(c.Output, `Usage:
+  wow view <key> [--raw] [--no-pager] [--redact]
+
+  wow! Renders a Markdown snippet in the terminal.
+
+  Headings, lists, code blocks and links are styled
+  and wrapped to the width of the terminal. Long
+  documents open in your pager, unless view =
+  false under [pager] in config.toml. Pass --raw
+  to get the source as it is. --redact masks secrets, as
+  it does for "wow get".
+
+  "wow open" uses this for Markdown snippets when
+  no [[open]] rule in config.toml matches them.`)
+		fmt.Fprintln(c.Output)
+		fs.PrintDefaults()
+		return nil
+	}
+
+	remaining := fs.Args()
+	if len(remaining) != 1 {
+		return errors.New("view expects exactly one key")
+	}
+
+	ctx := context.Background()
+	data, err := c.read(ctx, remaining[0])
+	if err != nil {
+		return err
+	}
+	if _, _, tty := c.screen(); redacting(fs, c.Redact, tty) {
+		data = orDefaultRules(c.Secrets).Redact(data, c.tagged(ctx, remaining[0]))
+	}
+	return c.show(ctx, data, *raw, *noPager)
+}
+
+// Show renders the snippet file at path, for open to use as a viewer.
+// The snippet's key is taken from the runner.Vars on ctx.
+func (c *ViewCommand) Show(ctx context.Context, path string) error {
+	if c.Output == nil {
+		return errors.New("view command not fully configured")
+	}
+	data, err := storage.Read(path)
+	if err != nil {
+		return err
+	}
+	if _, _, tty := c.screen(); c.Redact && tty {
+		data = orDefaultRules(c.Secrets).Redact(data, c.tagged(ctx, runner.VarsFrom(ctx).Key))
+	}
+	return c.show(ctx, data, false, false)
+}
+
+func (c *ViewCommand) show(ctx context.Context, data []byte, raw, noPager bool) error {
+	width, height, tty := c.screen()
+	if !raw {
+		wrap := width
+		if wrap <= 0 {
+			wrap = defaultViewWidth
+		}
+		rendered, err := ui.RenderMarkdown(data, wrap)
+		if err != nil {
+			return fmt.Errorf("render markdown: %w", err)
+		}
+		data = rendered
+	}
+
+	if !noPager && c.Pager != nil && tty && height > 0 && screenLines(data, width) >= height {
+		return c.Pager(ctx, bytes.NewReader(data))
+	}
+	if _, err := c.Output.Write(data); err != nil {
+		return fmt.Errorf("write snippet to output: %w", err)
+	}
+	return nil
+}
+
+// screen reports the size of Output, and whether it is a terminal.
+func (c *ViewCommand) screen() (int, int, bool) {
+	if c.Screen != nil {
+		return c.Screen()
+	}
+	if width, height, ok := terminalSize(c.Output); ok {
+		return width, height, ok
+	}
+	return writerWidth(c.Output), 0, false
+}
+
+// tagged reports whether the snippet is tagged services.SecretTag.
+func (c *ViewCommand) tagged(ctx context.Context, rawKey string) bool {
+	_, meta, err := c.Vaults.Lookup(ctx, rawKey)
+	return err == nil && services.HasSecretTag(meta.Tags)
+}
+
+// read loads the snippet's content across the vault search path.
+// Without configured vaults it falls back to BaseDir alone.
+func (c *ViewCommand) read(ctx context.Context, rawKey string) ([]byte, error) {
+	if len(c.Vaults) > 0 {
+		return c.Vaults.Read(ctx, rawKey, c.Passphrase)
+	}
+	path, err := key.ResolvePath(c.BaseDir, rawKey)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Read(path)
+}
@@ -0,0 +1,133 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"github.com/llywelwyn/wow/internal/runner"
+	"github.com/llywelwyn/wow/internal/services"
+)
+
+func TestViewCommandName(t *testing.T) {
+	if got := (&ViewCommand{}).Name(); got != "view" {
+		t.Fatalf("Name = %q, want view", got)
+	}
+}
+
+func TestViewCommandRequiresKey(t *testing.T) {
+	cmd := &ViewCommand{BaseDir: t.TempDir(), Output: &bytes.Buffer{}}
+	if err := cmd.Execute(nil); err == nil {
+		t.Fatalf("expected error for missing key")
+	}
+}
+
+func TestViewCommandRendersMarkdown(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.Ascii)
+
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+
+	content := "# Notes\n\n* one\n* two\n"
+	if _, err := saver.Save(context.Background(), services.SaveRequest{Key: "notes.md", Reader: strings.NewReader(content)}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	if err := NewViewCommand(cfg).Execute([]string{"notes.md"}); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if got := out.String(); got == content || !strings.Contains(got, "• one") {
+		t.Fatalf("output = %q, want rendered Markdown", got)
+	}
+
+	out.Reset()
+	if err := NewViewCommand(cfg).Execute([]string{"notes.md", "--raw"}); err != nil {
+		t.Fatalf("Execute --raw error = %v", err)
+	}
+	if out.String() != content {
+		t.Fatalf("raw output = %q, want %q", out.String(), content)
+	}
+}
+
+func TestViewCommandPagesTallOutputOnTerminal(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.Ascii)
+
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+
+	content := "# One\n\n## Two\n\n## Three\n"
+	if _, err := saver.Save(context.Background(), services.SaveRequest{Key: "long.md", Reader: strings.NewReader(content)}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	var paged bytes.Buffer
+	cfg.PageView = func(_ context.Context, r io.Reader) error {
+		_, err := io.Copy(&paged, r)
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		height int
+		tty    bool
+		paged  bool
+	}{
+		{"tall on terminal", []string{"long.md"}, 3, true, true},
+		{"fits on terminal", []string{"long.md"}, 100, true, false},
+		{"piped", []string{"long.md"}, 3, false, false},
+		{"no-pager", []string{"long.md", "--no-pager"}, 3, true, false},
+	}
+	for _, tc := range tests {
+		paged.Reset()
+		var out bytes.Buffer
+		cfg.Output = &out
+		cmd := NewViewCommand(cfg)
+		cmd.Screen = func() (int, int, bool) { return 80, tc.height, tc.tty }
+
+		if err := cmd.Execute(tc.args); err != nil {
+			t.Fatalf("%s: Execute error = %v", tc.name, err)
+		}
+		if got := paged.Len() > 0; got != tc.paged || (tc.paged == (out.Len() > 0)) {
+			t.Fatalf("%s: paged = %q, output = %q", tc.name, paged.String(), out.String())
+		}
+	}
+}
+
+func TestViewCommandShowRedactsTaggedSnippets(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.Ascii)
+
+	cfg, saver, cleanup := setupGetTest(t)
+	defer cleanup()
+
+	content := "db_host = prod.internal\n"
+	req := services.SaveRequest{Key: "db.md", Reader: strings.NewReader(content), Tags: []string{services.SecretTag}}
+	if _, err := saver.Save(context.Background(), req); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+	cfg.Redact = true
+
+	var out bytes.Buffer
+	cfg.Output = &out
+	cmd := NewViewCommand(cfg)
+	cmd.Screen = func() (int, int, bool) { return 80, 100, true }
+
+	ctx := runner.WithVars(context.Background(), runner.Vars{Key: "db.md"})
+	if err := cmd.Show(ctx, filepath.Join(cfg.BaseDir, "db.md")); err != nil {
+		t.Fatalf("Show error = %v", err)
+	}
+	if got := out.String(); strings.Contains(got, "prod.internal") || !strings.Contains(got, services.RedactMask) {
+		t.Fatalf("output = %q, want the tagged value masked", got)
+	}
+}
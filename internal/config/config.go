@@ -1,22 +1,194 @@
 // config manages configuration.
-// It captures required paths from ENV.
+// It captures required paths from ENV,
+// discovers project vaults from the working directory,
+// and reads optional settings from config.toml in the user vault.
 package config
 
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/BurntSushi/toml"
 )
 
+// FileName is the optional settings file kept in the user vault.
+const FileName = "config.toml"
+
+// ProjectDirName is the directory marking a project vault,
+// found by walking up from the working directory.
+const ProjectDirName = ".wow"
+
+// Names of the two vaults that can receive writes.
+const (
+	UserVault    = "user"
+	ProjectVault = "project"
+)
+
+// ErrNoProjectVault is returned when the local scope is requested
+// outside of a project.
+var ErrNoProjectVault = errors.New("no project vault found; run \"wow init\" to create one")
+
 // Config stores wow base directory
 // and the metadata DB paths.
+// BaseDir and MetaDB always describe the vault receiving writes.
 type Config struct {
 	BaseDir string
 	MetaDB  string
+	Vaults  []Vault // search path in precedence order; exactly one is writable.
+	Aliases map[string]string
+	Open    []OpenRule        // opener rules, tried in order.
+	Line    map[string]string // how to open at a line, by program name.
+	Pager   map[string]bool   // whether a command pages its output; on when unset.
+
+	MaxSnippetSize int64 // largest snippet save accepts, in bytes; zero for no limit.
+	CompressAbove  int64 // size from which snippets are stored gzipped; zero never compresses.
+
+	SecretPolicy string       // what saving content that looks secret does: warn, block, encrypt or off.
+	SecretRules  []SecretRule // patterns scanned for besides the built-in ones.
+	Redact       bool         // whether get, view and list mask secrets on a terminal by default.
+}
+
+// SecretRule is an extra pattern secret scanning looks for.
+type SecretRule struct {
+	Name    string `toml:"name"`
+	Pattern string `toml:"pattern"` // a regular expression, matched against each line.
 }
 
+// OpenRule routes matching snippets to a specific opener command.
+// Empty conditions match anything; every given condition must match.
+type OpenRule struct {
+	Name    string   `toml:"name"`
+	Keys    Patterns `toml:"key"`  // key globs, e.g. "*.md" or "sql/*".
+	Type    string   `toml:"type"` // snippet type, e.g. "url".
+	Lang    string   `toml:"lang"` // detected language, e.g. "markdown".
+	MIME    Patterns `toml:"mime"` // media type globs, e.g. "image/*".
+	Command string   `toml:"command"`
+}
+
+// Patterns holds one or more globs. In config.toml it may be
+// written as a single string or as an array of strings.
+type Patterns []string
+
+// UnmarshalTOML accepts a string or an array of strings.
+func (p *Patterns) UnmarshalTOML(value any) error {
+	switch v := value.(type) {
+	case string:
+		*p = Patterns{v}
+	case []any:
+		out := make(Patterns, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("pattern %v: must be a string", item)
+			}
+			out = append(out, s)
+		}
+		*p = out
+	default:
+		return fmt.Errorf("patterns must be a string or array of strings, got %T", value)
+	}
+	return nil
+}
+
+// ByteSize is a number of bytes. In config.toml it may be written as
+// an integer or with a unit, e.g. "10MB" or "512 KiB". Units are
+// powers of 1024.
+type ByteSize int64
+
+// byteUnits are the units ByteSize accepts, longest first.
+var byteUnits = []struct {
+	suffix string
+	scale  int64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30},
+	{"b", 1},
+}
+
+// UnmarshalTOML accepts an integer or a string such as "10MB".
+func (b *ByteSize) UnmarshalTOML(value any) error {
+	var n int64
+	switch v := value.(type) {
+	case int64:
+		n = v
+	case string:
+		parsed, err := ParseByteSize(v)
+		if err != nil {
+			return err
+		}
+		n = parsed
+	default:
+		return fmt.Errorf("size must be an integer or a string such as \"10MB\", got %T", value)
+	}
+	if n < 0 {
+		return fmt.Errorf("size %v: must not be negative", value)
+	}
+	*b = ByteSize(n)
+	return nil
+}
+
+// ParseByteSize reads a size such as "10MB", "512 KiB" or "300".
+func ParseByteSize(s string) (int64, error) {
+	text := strings.ToLower(strings.TrimSpace(s))
+	scale := int64(1)
+	for _, unit := range byteUnits {
+		if rest, ok := strings.CutSuffix(text, unit.suffix); ok {
+			text, scale = strings.TrimSpace(rest), unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("size %q: want a number of bytes such as \"10MB\"", s)
+	}
+	if n > math.MaxInt64/scale || n < math.MinInt64/scale {
+		return 0, fmt.Errorf("size %q: too large", s)
+	}
+	return n * scale, nil
+}
+
+// file mirrors the layout of config.toml.
+type file struct {
+	Alias          map[string]string `toml:"alias"`
+	Open           []OpenRule        `toml:"open"`
+	Line           map[string]string `toml:"line"`
+	Pager          map[string]bool   `toml:"pager"`
+	MaxSnippetSize ByteSize          `toml:"max_snippet_size"`
+	CompressAbove  ByteSize          `toml:"compress_above"`
+	Secrets        secrets           `toml:"secrets"`
+}
+
+// secrets mirrors the [secrets] table of config.toml.
+type secrets struct {
+	Policy string       `toml:"policy"`
+	Redact bool         `toml:"redact"`
+	Rules  []SecretRule `toml:"rule"`
+}
+
+// Vault describes one snippet store in the search path.
+type Vault struct {
+	Name     string
+	BaseDir  string
+	MetaDB   string
+	ReadOnly bool
+}
+
+// Scope selects which vault receives writes.
+type Scope int
+
+const (
+	ScopeDefault Scope = iota // the project vault when there is one, else the user vault.
+	ScopeGlobal               // the user vault.
+	ScopeLocal                // the project vault.
+)
+
 // Load resolves configuration from environment
 // and ensures required directories exist.
 func Load() (Config, error) {
@@ -29,12 +201,272 @@ func Load() (Config, error) {
 		return Config{}, fmt.Errorf("create base dir %q: %w", base, err)
 	}
 
-	return Config{
+	user := Vault{
+		Name:    UserVault,
 		BaseDir: base,
 		MetaDB:  filepath.Join(base, "meta.db"),
+	}
+
+	var vaults []Vault
+	project, err := resolveProjectVault(user)
+	if err != nil {
+		return Config{}, err
+	}
+	if project != nil {
+		vaults = append(vaults, *project)
+	}
+	vaults = append(vaults, user)
+
+	shared, err := resolveSharedVaults(vaults)
+	if err != nil {
+		return Config{}, err
+	}
+
+	settings, err := readFile(filepath.Join(base, FileName))
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := validateOpenRules(settings.Open); err != nil {
+		return Config{}, err
+	}
+	for i, rule := range settings.Secrets.Rules {
+		if strings.TrimSpace(rule.Name) == "" || rule.Pattern == "" {
+			return Config{}, fmt.Errorf("config: secrets rule %d: name and pattern required", i+1)
+		}
+	}
+	for program, template := range settings.Line {
+		if !strings.Contains(template, "{line}") {
+			return Config{}, fmt.Errorf("config: line template for %q: must contain {line}", program)
+		}
+	}
+
+	cfg := Config{
+		Vaults:  append(vaults, shared...),
+		Aliases: settings.Alias,
+		Open:    settings.Open,
+		Line:    settings.Line,
+		Pager:   settings.Pager,
+
+		MaxSnippetSize: int64(settings.MaxSnippetSize),
+		CompressAbove:  int64(settings.CompressAbove),
+
+		SecretPolicy: settings.Secrets.Policy,
+		SecretRules:  settings.Secrets.Rules,
+		Redact:       settings.Secrets.Redact,
+	}
+	return cfg.WithScope(ScopeDefault)
+}
+
+// Paging reports whether the named command should page long output.
+func (c Config) Paging(command string) bool {
+	enabled, set := c.Pager[command]
+	return enabled || !set
+}
+
+// readFile decodes the settings file at path.
+// A missing file is not an error; it just yields no settings.
+func readFile(path string) (file, error) {
+	var f file
+	if _, err := toml.DecodeFile(path, &f); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return file{}, nil
+		}
+		return file{}, fmt.Errorf("read config %q: %w", path, err)
+	}
+	return f, nil
+}
+
+// validateOpenRules checks every rule can run and be told apart by name.
+func validateOpenRules(rules []OpenRule) error {
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Name) == "" {
+			return fmt.Errorf("config: open rule %d: name required", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("config: open rule %q: duplicate name", rule.Name)
+		}
+		names[rule.Name] = true
+		if strings.TrimSpace(rule.Command) == "" {
+			return fmt.Errorf("config: open rule %q: command required", rule.Name)
+		}
+		for _, pattern := range rule.Keys {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("config: open rule %q: key %q: %w", rule.Name, pattern, err)
+			}
+		}
+		for _, pattern := range rule.MIME {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("config: open rule %q: mime %q: %w", rule.Name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WithScope returns a copy of c whose writes go to the vault picked by scope.
+// Every other vault in the search path is marked read-only, so commands
+// refuse to write to it; that says nothing of whether it can be written,
+// which is what Writable reports.
+func (c Config) WithScope(scope Scope) (Config, error) {
+	target := UserVault
+	switch scope {
+	case ScopeLocal:
+		target = ProjectVault
+	case ScopeDefault:
+		for _, v := range c.Vaults {
+			if v.Name == ProjectVault {
+				target = ProjectVault
+				break
+			}
+		}
+	}
+
+	scoped := c
+	scoped.Vaults = make([]Vault, len(c.Vaults))
+	copy(scoped.Vaults, c.Vaults)
+
+	found := false
+	for i, v := range scoped.Vaults {
+		switch v.Name {
+		case target:
+			scoped.Vaults[i].ReadOnly = false
+			scoped.BaseDir = v.BaseDir
+			scoped.MetaDB = v.MetaDB
+			found = true
+		case UserVault, ProjectVault:
+			scoped.Vaults[i].ReadOnly = true
+		}
+	}
+	if !found {
+		if target == ProjectVault {
+			return Config{}, ErrNoProjectVault
+		}
+		return Config{}, fmt.Errorf("no %s vault configured", target)
+	}
+	return scoped, nil
+}
+
+// Writable reports whether the vault's files and database may be
+// written at all. The user and project vaults always may, whichever of
+// them the scope sends writes to; shared vaults never may.
+func (v Vault) Writable() bool {
+	return v.Name == UserVault || v.Name == ProjectVault
+}
+
+// UserDir returns the user vault directory, whichever vault receives writes.
+func (c Config) UserDir() string {
+	for _, v := range c.Vaults {
+		if v.Name == UserVault {
+			return v.BaseDir
+		}
+	}
+	return c.BaseDir
+}
+
+// resolveProjectVault walks up from the working directory looking for a
+// project vault, the way git looks for .git. The user vault itself and
+// the default ~/.wow are never mistaken for a project.
+func resolveProjectVault(user Vault) (*Vault, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("resolve working directory: %w", err)
+	}
+
+	skip := map[string]bool{user.BaseDir: true}
+	if home, err := os.UserHomeDir(); err == nil && strings.TrimSpace(home) != "" {
+		skip[filepath.Join(home, ProjectDirName)] = true
+	}
+
+	dir := FindProjectDir(wd, skip)
+	if dir == "" {
+		return nil, nil
+	}
+	return &Vault{
+		Name:    ProjectVault,
+		BaseDir: dir,
+		MetaDB:  filepath.Join(dir, "meta.db"),
 	}, nil
 }
 
+// FindProjectDir returns the nearest .wow directory at or above start,
+// ignoring any path in skip. It returns "" when there is none.
+func FindProjectDir(start string, skip map[string]bool) string {
+	dir := filepath.Clean(start)
+	for {
+		candidate := filepath.Join(dir, ProjectDirName)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() && !skip[candidate] {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// resolveSharedVaults reads the read-only vaults layered beneath the user vault.
+//
+// $WOW_VAULTS holds a list of directories separated like $PATH. Each one is
+// named after its final path element, suffixed with a number if that name
+// is already taken. Shared vaults are never created; they must already exist.
+func resolveSharedVaults(existing []Vault) ([]Vault, error) {
+	raw := strings.TrimSpace(os.Getenv("WOW_VAULTS"))
+	if raw == "" {
+		return nil, nil
+	}
+
+	taken := map[string]bool{UserVault: true, ProjectVault: true}
+	seen := make(map[string]bool)
+	for _, v := range existing {
+		seen[v.BaseDir] = true
+	}
+
+	var vaults []Vault
+	for _, entry := range filepath.SplitList(raw) {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		dir, err := normalizeDir(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("shared vault %q: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("shared vault %q: not a directory", dir)
+		}
+
+		vaults = append(vaults, Vault{
+			Name:     uniqueName(filepath.Base(dir), taken),
+			BaseDir:  dir,
+			MetaDB:   filepath.Join(dir, "meta.db"),
+			ReadOnly: true,
+		})
+	}
+	return vaults, nil
+}
+
+// uniqueName returns name, or name with the lowest free numeric suffix.
+// The chosen name is recorded in taken.
+func uniqueName(name string, taken map[string]bool) string {
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
 // resolveBaseDir figures out the base directory we want to use.
 //
 // It falls back through the following directories in order:
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -77,3 +81,367 @@ func TestLoadExpandsTilde(t *testing.T) {
 		t.Fatalf("BaseDir = %q, want %q", cfg.BaseDir, want)
 	}
 }
+
+func TestLoadLayersSharedVaults(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "wowhome")
+	team := filepath.Join(t.TempDir(), "snippets")
+	other := filepath.Join(t.TempDir(), "snippets")
+	for _, dir := range []string{team, other} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatalf("MkdirAll error = %v", err)
+		}
+	}
+
+	t.Setenv("WOW_HOME", home)
+	t.Setenv("WOW_VAULTS", strings.Join([]string{team, other, team}, string(filepath.ListSeparator)))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(cfg.Vaults) != 3 {
+		t.Fatalf("len(Vaults) = %d, want 3", len(cfg.Vaults))
+	}
+
+	primary := cfg.Vaults[0]
+	if primary.BaseDir != cfg.BaseDir || primary.MetaDB != cfg.MetaDB || primary.ReadOnly {
+		t.Fatalf("primary vault = %+v, want writable vault at BaseDir", primary)
+	}
+
+	if got := cfg.Vaults[1]; got.Name != "snippets" || got.BaseDir != team || !got.ReadOnly {
+		t.Fatalf("Vaults[1] = %+v, want read-only %q named snippets", got, team)
+	}
+	if got := cfg.Vaults[2]; got.Name != "snippets-2" || got.BaseDir != other {
+		t.Fatalf("Vaults[2] = %+v, want %q named snippets-2", got, other)
+	}
+}
+
+func TestLoadRejectsMissingSharedVault(t *testing.T) {
+	t.Setenv("WOW_HOME", filepath.Join(t.TempDir(), "wowhome"))
+	t.Setenv("WOW_VAULTS", filepath.Join(t.TempDir(), "missing"))
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("expected error for missing shared vault")
+	}
+}
+
+func TestLoadDiscoversProjectVault(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "wowhome")
+	repo := t.TempDir()
+	project := filepath.Join(repo, ProjectDirName)
+	nested := filepath.Join(repo, "src", "pkg")
+	for _, dir := range []string{project, nested} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatalf("MkdirAll error = %v", err)
+		}
+	}
+
+	t.Setenv("WOW_HOME", home)
+	t.Setenv("WOW_VAULTS", "")
+	t.Chdir(nested)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(cfg.Vaults) != 2 {
+		t.Fatalf("len(Vaults) = %d, want 2", len(cfg.Vaults))
+	}
+	if got := cfg.Vaults[0]; got.Name != ProjectVault || got.BaseDir != project || got.ReadOnly {
+		t.Fatalf("Vaults[0] = %+v, want writable project vault at %q", got, project)
+	}
+	if got := cfg.Vaults[1]; got.Name != UserVault || !got.ReadOnly {
+		t.Fatalf("Vaults[1] = %+v, want read-only user vault", got)
+	}
+	if cfg.BaseDir != project {
+		t.Fatalf("BaseDir = %q, want %q", cfg.BaseDir, project)
+	}
+	if cfg.UserDir() != home {
+		t.Fatalf("UserDir() = %q, want %q", cfg.UserDir(), home)
+	}
+
+	global, err := cfg.WithScope(ScopeGlobal)
+	if err != nil {
+		t.Fatalf("WithScope(ScopeGlobal) error = %v", err)
+	}
+	if global.BaseDir != home || global.Vaults[1].ReadOnly || !global.Vaults[0].ReadOnly {
+		t.Fatalf("global scope = %+v, want writes to user vault", global)
+	}
+	if global.Vaults[0].Name != ProjectVault {
+		t.Fatalf("global scope should keep search order, got %+v", global.Vaults)
+	}
+}
+
+func TestWithScopeLocalRequiresProject(t *testing.T) {
+	t.Setenv("WOW_HOME", filepath.Join(t.TempDir(), "wowhome"))
+	t.Setenv("WOW_VAULTS", "")
+	t.Chdir(t.TempDir())
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if _, err := cfg.WithScope(ScopeLocal); !errors.Is(err, ErrNoProjectVault) {
+		t.Fatalf("WithScope(ScopeLocal) error = %v, want ErrNoProjectVault", err)
+	}
+}
+
+func TestFindProjectDirSkipsUserVault(t *testing.T) {
+	home := t.TempDir()
+	userVault := filepath.Join(home, ProjectDirName)
+	nested := filepath.Join(home, "code")
+	for _, dir := range []string{userVault, nested} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatalf("MkdirAll error = %v", err)
+		}
+	}
+
+	if got := FindProjectDir(nested, map[string]bool{userVault: true}); got != "" {
+		t.Fatalf("FindProjectDir = %q, want none", got)
+	}
+	if got := FindProjectDir(nested, nil); got != userVault {
+		t.Fatalf("FindProjectDir = %q, want %q", got, userVault)
+	}
+}
+
+func TestLoadReadsAliases(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("WOW_HOME", home)
+	t.Setenv("WOW_VAULTS", "")
+
+	contents := "[alias]\nurls = \"list --type --verbose\"\nhi = \"!echo hi\"\n"
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte(contents), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.Aliases["urls"]; got != "list --type --verbose" {
+		t.Fatalf("Aliases[urls] = %q", got)
+	}
+	if got := cfg.Aliases["hi"]; got != "!echo hi" {
+		t.Fatalf("Aliases[hi] = %q", got)
+	}
+}
+
+func TestLoadRejectsMalformedConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("WOW_HOME", home)
+	t.Setenv("WOW_VAULTS", "")
+
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte("[alias\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	if _, err := Load(); err == nil {
+		t.Fatalf("expected error for malformed config")
+	}
+}
+
+func TestLoadReadsOpenRules(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("WOW_HOME", home)
+	t.Setenv("WOW_VAULTS", "")
+
+	contents := `[[open]]
+name = "markdown"
+key = ["*.md", "*.markdown"]
+command = "glow {path}"
+
+[[open]]
+name = "links"
+type = "url"
+key = "work/*"
+command = "firefox --new-tab"
+
+[[open]]
+name = "images"
+mime = ["image/*", "application/pdf"]
+command = "xdg-open"
+`
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte(contents), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(cfg.Open) != 3 {
+		t.Fatalf("len(Open) = %d, want 3", len(cfg.Open))
+	}
+	if got := cfg.Open[0]; got.Name != "markdown" || len(got.Keys) != 2 || got.Command != "glow {path}" {
+		t.Fatalf("Open[0] = %+v", got)
+	}
+	if got := cfg.Open[1]; got.Type != "url" || len(got.Keys) != 1 || got.Keys[0] != "work/*" {
+		t.Fatalf("Open[1] = %+v", got)
+	}
+	if got := cfg.Open[2]; len(got.MIME) != 2 || got.MIME[0] != "image/*" {
+		t.Fatalf("Open[2] = %+v", got)
+	}
+}
+
+func TestLoadRejectsInvalidOpenRules(t *testing.T) {
+	cases := map[string]string{
+		"missing name":    "[[open]]\ncommand = \"less\"\n",
+		"missing command": "[[open]]\nname = \"x\"\n",
+		"duplicate name":  "[[open]]\nname = \"x\"\ncommand = \"a\"\n[[open]]\nname = \"x\"\ncommand = \"b\"\n",
+		"bad glob":        "[[open]]\nname = \"x\"\nkey = \"[\"\ncommand = \"a\"\n",
+		"bad mime glob":   "[[open]]\nname = \"x\"\nmime = \"[\"\ncommand = \"a\"\n",
+	}
+	for name, contents := range cases {
+		t.Run(name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("WOW_HOME", home)
+			t.Setenv("WOW_VAULTS", "")
+
+			if err := os.WriteFile(filepath.Join(home, FileName), []byte(contents), 0o600); err != nil {
+				t.Fatalf("WriteFile error = %v", err)
+			}
+			if _, err := Load(); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadReadsLineTemplates(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("WOW_HOME", home)
+	t.Setenv("WOW_VAULTS", "")
+
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte("[line]\nmyeditor = \"--goto {path}:{line}\"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Line["myeditor"]; got != "--goto {path}:{line}" {
+		t.Fatalf("Line[myeditor] = %q", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte("[line]\nmyeditor = \"--goto {path}\"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Fatalf("expected error for a template without {line}")
+	}
+}
+
+func TestPagingDefaultsOn(t *testing.T) {
+	if !(Config{}).Paging("get") {
+		t.Fatalf("Paging(get) = false, want true when unset")
+	}
+	cfg := Config{Pager: map[string]bool{"get": false}}
+	if cfg.Paging("get") {
+		t.Fatalf("Paging(get) = true, want false when disabled")
+	}
+}
+
+func TestLoadReadsMaxSnippetSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{`1048576`, 1 << 20},
+		{`"10MB"`, 10 << 20},
+		{`"512 KiB"`, 512 << 10},
+		{`"1g"`, 1 << 30},
+		{`"300"`, 300},
+	}
+	for _, tt := range tests {
+		home := t.TempDir()
+		t.Setenv("WOW_HOME", home)
+		t.Setenv("WOW_VAULTS", "")
+
+		contents := "max_snippet_size = " + tt.value + "\n"
+		if err := os.WriteFile(filepath.Join(home, FileName), []byte(contents), 0o600); err != nil {
+			t.Fatalf("WriteFile error = %v", err)
+		}
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load(%s) error = %v", tt.value, err)
+		}
+		if cfg.MaxSnippetSize != tt.want {
+			t.Fatalf("MaxSnippetSize for %s = %d, want %d", tt.value, cfg.MaxSnippetSize, tt.want)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidMaxSnippetSize(t *testing.T) {
+	for _, value := range []string{`-1`, `"ten MB"`, `"5 TB"`, `true`} {
+		home := t.TempDir()
+		t.Setenv("WOW_HOME", home)
+		t.Setenv("WOW_VAULTS", "")
+
+		contents := "max_snippet_size = " + value + "\n"
+		if err := os.WriteFile(filepath.Join(home, FileName), []byte(contents), 0o600); err != nil {
+			t.Fatalf("WriteFile error = %v", err)
+		}
+
+		if _, err := Load(); err == nil {
+			t.Fatalf("expected error for max_snippet_size = %s", value)
+		}
+	}
+}
+
+func TestLoadReadsCompressAbove(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("WOW_HOME", home)
+	t.Setenv("WOW_VAULTS", "")
+
+	contents := "compress_above = \"64KB\"\n"
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte(contents), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.CompressAbove != 64<<10 {
+		t.Fatalf("CompressAbove = %d, want %d", cfg.CompressAbove, 64<<10)
+	}
+}
+
+func TestLoadReadsSecrets(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("WOW_HOME", home)
+	t.Setenv("WOW_VAULTS", "")
+
+	contents := "[secrets]\npolicy = \"block\"\nredact = true\n\n[[secrets.rule]]\nname = \"internal token\"\npattern = \"itk_[0-9a-f]{32}\"\n"
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte(contents), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SecretPolicy != "block" {
+		t.Fatalf("SecretPolicy = %q, want block", cfg.SecretPolicy)
+	}
+	if !cfg.Redact {
+		t.Fatal("Redact = false, want true")
+	}
+	want := []SecretRule{{Name: "internal token", Pattern: "itk_[0-9a-f]{32}"}}
+	if !slices.Equal(cfg.SecretRules, want) {
+		t.Fatalf("SecretRules = %v, want %v", cfg.SecretRules, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte("[[secrets.rule]]\nname = \"empty\"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() accepted a rule without a pattern")
+	}
+}
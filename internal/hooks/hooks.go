@@ -0,0 +1,130 @@
+// hooks runs user scripts around snippet lifecycle events.
+// Hooks are executables named after the event, kept in one directory,
+// e.g. $WOW_HOME/hooks/pre-save. Each receives the snippet as WOW_*
+// environment variables and as JSON on stdin.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/model"
+)
+
+// Lifecycle events with a hook.
+const (
+	PreSave    = "pre-save"
+	PostSave   = "post-save"
+	PreEdit    = "pre-edit"
+	PostEdit   = "post-edit"
+	PreRemove  = "pre-remove"
+	PostRemove = "post-remove"
+	PostOpen   = "post-open"
+)
+
+// ErrAborted is returned when a pre- hook exits non-zero.
+var ErrAborted = errors.New("aborted by hook")
+
+// Payload describes the snippet an event concerns.
+type Payload struct {
+	Event    string   `json:"event"`
+	Key      string   `json:"key"`
+	Path     string   `json:"path"` // the snippet file; for pre-save, a temp file with the new content.
+	Metadata Metadata `json:"metadata"`
+}
+
+// Metadata is the JSON form of model.Metadata given to hooks.
+type Metadata struct {
+	Type        string    `json:"type"`
+	Language    string    `json:"language,omitempty"`
+	MIME        string    `json:"mime,omitempty"`
+	Size        int64     `json:"size"`
+	Encoding    string    `json:"encoding,omitempty"` // how the file at Path is stored, e.g. "gzip".
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+	Description string    `json:"description"`
+	Tags        string    `json:"tags"`
+}
+
+// NewPayload describes meta, stored at path, for event.
+func NewPayload(event, path string, meta model.Metadata) Payload {
+	return Payload{
+		Event: event,
+		Key:   meta.Key,
+		Path:  path,
+		Metadata: Metadata{
+			Type:        meta.Type,
+			Language:    meta.Language,
+			MIME:        meta.MIME,
+			Size:        meta.Size,
+			Encoding:    meta.Encoding,
+			Created:     meta.Created.UTC(),
+			Modified:    meta.Modified.UTC(),
+			Description: meta.Description,
+			Tags:        meta.Tags,
+		},
+	}
+}
+
+// Runner runs the hooks found in Dir.
+// A nil Runner runs nothing, which is how hooks are disabled.
+type Runner struct {
+	Dir string
+}
+
+// Run runs the hook for p.Event, if there is one.
+//
+// A pre- hook exiting non-zero aborts the operation: Run returns an
+// error wrapping ErrAborted. Like git, the exit status of post- hooks
+// is ignored since the operation has already happened.
+func (r *Runner) Run(ctx context.Context, p Payload) error {
+	if r == nil || r.Dir == "" {
+		return nil
+	}
+
+	path := filepath.Join(r.Dir, p.Event)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return nil
+	}
+
+	stdin, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encode %s hook payload: %w", p.Event, err)
+	}
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(),
+		"WOW_HOOK="+p.Event,
+		"WOW_KEY="+p.Key,
+		"WOW_FILE="+p.Path,
+		"WOW_TYPE="+p.Metadata.Type,
+		"WOW_TAGS="+p.Metadata.Tags,
+		"WOW_DESCRIPTION="+p.Metadata.Description,
+	)
+	cmd.Stdin = bytes.NewReader(stdin)
+	// Hooks talk to the user, never to whoever reads wow's stdout.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err == nil || !isPre(p.Event) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s: %v", ErrAborted, p.Event, err)
+}
+
+func isPre(event string) bool {
+	switch event {
+	case PreSave, PreEdit, PreRemove:
+		return true
+	}
+	return false
+}
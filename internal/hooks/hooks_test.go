@@ -0,0 +1,88 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/model"
+)
+
+func writeHook(t *testing.T, dir, event, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, event), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+}
+
+func testPayload(event string) Payload {
+	now := time.Unix(1_700_000_000, 0)
+	return NewPayload(event, "/vault/go/foo", model.Metadata{
+		Key:      "go/foo",
+		Type:     "text",
+		Created:  now,
+		Modified: now,
+		Tags:     "a,b",
+	})
+}
+
+func TestRunPassesEnvAndJSON(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	writeHook(t, dir, PostSave, `printf '%s\n%s\n' "$WOW_KEY" "$WOW_FILE" > "`+out+`.env"; cat > "`+out+`.json"`)
+
+	r := &Runner{Dir: dir}
+	if err := r.Run(context.Background(), testPayload(PostSave)); err != nil {
+		t.Fatalf("Run error = %v", err)
+	}
+
+	env, err := os.ReadFile(out + ".env")
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if string(env) != "go/foo\n/vault/go/foo\n" {
+		t.Fatalf("hook env = %q", env)
+	}
+
+	raw, err := os.ReadFile(out + ".json")
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	var got Payload
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if got.Event != PostSave || got.Key != "go/foo" || got.Metadata.Tags != "a,b" {
+		t.Fatalf("hook payload = %+v", got)
+	}
+}
+
+func TestRunPreHookFailureAborts(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, PreSave, "exit 3")
+	writeHook(t, dir, PostSave, "exit 3")
+
+	r := &Runner{Dir: dir}
+	if err := r.Run(context.Background(), testPayload(PreSave)); !errors.Is(err, ErrAborted) {
+		t.Fatalf("pre-save error = %v, want ErrAborted", err)
+	}
+	if err := r.Run(context.Background(), testPayload(PostSave)); err != nil {
+		t.Fatalf("post-save error = %v, want nil", err)
+	}
+}
+
+func TestRunWithoutHooks(t *testing.T) {
+	var nilRunner *Runner
+	if err := nilRunner.Run(context.Background(), testPayload(PreSave)); err != nil {
+		t.Fatalf("nil Runner error = %v", err)
+	}
+
+	r := &Runner{Dir: filepath.Join(t.TempDir(), "missing")}
+	if err := r.Run(context.Background(), testPayload(PreSave)); err != nil {
+		t.Fatalf("missing hook error = %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -68,6 +69,21 @@ func Normalize(raw string) (string, error) {
 	return key, nil
 }
 
+// SplitLine separates a trailing ":line" from raw, as in "ops/deploy:120".
+// Keys cannot contain ":", so anything else is left for Normalize to reject.
+// It returns a line of zero when there is no suffix.
+func SplitLine(raw string) (string, int, error) {
+	k, suffix, found := strings.Cut(raw, ":")
+	if !found {
+		return raw, 0, nil
+	}
+	line, err := strconv.Atoi(suffix)
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("%w: line %q after \":\" must be a positive number", ErrBadSegment, suffix)
+	}
+	return k, line, nil
+}
+
 // validateSegment checks if a key segment is valid.
 //
 // It returns one of the following errors:
@@ -29,3 +29,29 @@ func TestResolvePathRejectsTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		raw  string
+		key  string
+		line int
+	}{
+		{"ops/deploy", "ops/deploy", 0},
+		{"ops/deploy:120", "ops/deploy", 120},
+	}
+	for _, tc := range tests {
+		k, line, err := SplitLine(tc.raw)
+		if err != nil {
+			t.Fatalf("SplitLine(%q) error = %v", tc.raw, err)
+		}
+		if k != tc.key || line != tc.line {
+			t.Fatalf("SplitLine(%q) = %q, %d, want %q, %d", tc.raw, k, line, tc.key, tc.line)
+		}
+	}
+
+	for _, raw := range []string{"ops/deploy:", "ops/deploy:abc", "ops/deploy:0", "a:1:2"} {
+		if _, _, err := SplitLine(raw); err == nil {
+			t.Fatalf("SplitLine(%q) expected error", raw)
+		}
+	}
+}
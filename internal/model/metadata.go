@@ -6,17 +6,83 @@ import "time"
 type Metadata struct {
 	Key         string
 	Type        string
+	Language    string // e.g. "go" or "bash"; empty when unknown.
+	MIME        string // media type of the content, e.g. "image/png".
+	Size        int64  // length of the content in bytes.
+	Encoding    string // how the file is stored, e.g. "gzip"; empty when as is.
+	SHA256      string // hex SHA-256 of the content; empty when unknown.
 	Created     time.Time
 	Modified    time.Time
 	Description string
 	Tags        string
+	Fields      map[string]string // custom fields set by the user.
+	Protected   bool              // refuses edits, removal, overwrites and tag changes without --force.
+	Vault       string            // name of the vault the entry was read from; not persisted.
 }
 
+// TypeIcon returns a short label for the snippet's type, naming the
+// language of code and scripts when it has one.
 func (m *Metadata) TypeIcon() string {
 	switch m.Type {
 	case "url":
 		return "url"
+	case "code", "script":
+		if icon, ok := languageIcons[m.Language]; ok {
+			return icon
+		}
+		if m.Type == "script" {
+			return "#!"
+		}
+		return "</>"
+	case "json":
+		return "{}"
+	case "yaml":
+		return "yml"
+	case "toml":
+		return "tml"
+	case "markdown":
+		return "md"
+	case "sql":
+		return "sql"
+	case "diff":
+		return "+/-"
+	case "binary":
+		return "bin"
 	default:
 		return "txt"
 	}
 }
+
+// TypeLabel returns the type, followed by the language when it adds
+// to it, as in "code/go".
+func (m *Metadata) TypeLabel() string {
+	if m.Language == "" || m.Language == m.Type {
+		return m.Type
+	}
+	return m.Type + "/" + m.Language
+}
+
+// languageIcons are the icons of languages, mostly their extension.
+var languageIcons = map[string]string{
+	"bash":       "sh",
+	"c":          "c",
+	"cpp":        "c++",
+	"csharp":     "c#",
+	"css":        "css",
+	"fish":       "sh",
+	"go":         "go",
+	"html":       "htm",
+	"java":       "jav",
+	"javascript": "js",
+	"kotlin":     "kt",
+	"lua":        "lua",
+	"perl":       "pl",
+	"php":        "php",
+	"python":     "py",
+	"ruby":       "rb",
+	"rust":       "rs",
+	"shell":      "sh",
+	"swift":      "swf",
+	"typescript": "ts",
+	"zsh":        "sh",
+}
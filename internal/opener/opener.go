@@ -11,5 +11,11 @@ func GetOpenerFromEnv() string {
 	if cmd := strings.TrimSpace(os.Getenv("WOW_OPENER")); cmd != "" {
 		return cmd
 	}
+	return SystemOpener()
+}
+
+// SystemOpener returns the command that opens a file with the program
+// the desktop associates with its media type, whatever WOW_OPENER says.
+func SystemOpener() string {
 	return "xdg-open"
 }
@@ -15,3 +15,10 @@ func TestGetOpenerFromEnvFallback(t *testing.T) {
 		t.Fatalf("GetOpenerFromEnv() = %q, want xdg-open", got)
 	}
 }
+
+func TestSystemOpenerIgnoresEnv(t *testing.T) {
+	t.Setenv("WOW_OPENER", "custom-open")
+	if got := SystemOpener(); got != "xdg-open" {
+		t.Fatalf("SystemOpener() = %q, want xdg-open", got)
+	}
+}
@@ -16,3 +16,18 @@ func GetPagerFromEnv() string {
 	}
 	return "less"
 }
+
+// Env returns environment defaults for a pager reading piped output.
+// Like git, less is told to quit when everything fits on one screen,
+// to pass colours through and to leave the screen alone on exit,
+// unless LESS is already set.
+func Env() []string {
+	var env []string
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		env = append(env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		env = append(env, "LV=-c")
+	}
+	return env
+}
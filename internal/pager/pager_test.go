@@ -25,3 +25,12 @@ func TestGetPagerFromEnvDefaults(t *testing.T) {
 		t.Fatalf("GetPagerFromEnv() = %q, want %q", got, "less")
 	}
 }
+
+func TestEnvKeepsUserLess(t *testing.T) {
+	t.Setenv("LESS", "-R")
+	for _, kv := range Env() {
+		if kv == "LESS=FRX" {
+			t.Fatalf("Env() overrides LESS: %v", Env())
+		}
+	}
+}
@@ -3,25 +3,172 @@ package runner
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+// Vars fill the placeholders of a command template.
+// Callers attach them to the context passed to a command with WithVars.
+type Vars struct {
+	Path string // the snippet file.
+	Key  string // the snippet key.
+	Line int    // line to jump to; zero when there is none.
+	URL  string // the URL a url snippet points at.
+
+	Files []string // more files to open alongside the target.
+}
+
+type varsKey struct{}
+
+// WithVars returns a copy of ctx carrying vars for command templates.
+func WithVars(ctx context.Context, vars Vars) context.Context {
+	return context.WithValue(ctx, varsKey{}, vars)
+}
+
+// VarsFrom returns the Vars attached to ctx by WithVars, if any.
+func VarsFrom(ctx context.Context) Vars {
+	vars, _ := ctx.Value(varsKey{}).(Vars)
+	return vars
+}
+
+// placeholder matches the template placeholders Command understands.
+var placeholder = regexp.MustCompile(`\{(path|key|line|url)\}`)
+
+// shellWord matches a placeholder in a shell script with the rest of the
+// word around it, up to blanks or shell operators.
+var shellWord = regexp.MustCompile(`[^\s;&|()<>]*\{(path|key|line|url)\}[^\s;&|()<>]*`)
+
+// envAssignment matches a leading NAME=value word.
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// DefaultLineTemplates tell Command how to open a file at a line with
+// well-known programs whose command has no {line} placeholder, keyed by
+// program name. The template replaces the trailing target. Callers clone
+// it to add their own rather than modifying it.
+var DefaultLineTemplates = map[string]string{
+	"vi":          "+{line} {path}",
+	"vim":         "+{line} {path}",
+	"nvim":        "+{line} {path}",
+	"gvim":        "+{line} {path}",
+	"nano":        "+{line} {path}",
+	"emacs":       "+{line} {path}",
+	"emacsclient": "+{line} {path}",
+	"micro":       "+{line} {path}",
+	"kak":         "+{line} {path}",
+	"joe":         "+{line} {path}",
+	"less":        "+{line} {path}",
+	"code":        "-g {path}:{line}",
+	"codium":      "-g {path}:{line}",
+	"cursor":      "-g {path}:{line}",
+	"subl":        "{path}:{line}",
+	"zed":         "{path}:{line}",
+	"hx":          "{path}:{line}",
+	"helix":       "{path}:{line}",
+}
+
 // Command returns a callable that executes the provided command string with the target argument.
-func Command(command string) (func(context.Context, string) error, error) {
+//
+// The command is split into words like a POSIX shell would, so quoted
+// paths with spaces work, and leading NAME=value words set environment
+// variables. Words may hold {path}, {key}, {line} or {url} placeholders,
+// filled from the Vars on the context; {path} and {url} fall back to the
+// target. A word whose placeholder has no value is dropped, so "+{line}"
+// vanishes when there is no line. Without a {path} or {url} placeholder,
+// the target is appended as the final argument, or, when Vars has a line
+// and the program has an entry in lines, the template for it.
+//
+// When Vars lists further Files, they follow the target, and a word
+// holding {path} is repeated for each of them. Lines are ignored then.
+//
+// A command starting with "!" is run with sh -c instead. Placeholders
+// are substituted shell-quoted, and the blank-separated word around an
+// empty one is dropped, as above. The target is passed as "$@" when the
+// script has no {path} or {url} of its own.
+func Command(command string, lines map[string]string) (func(context.Context, string) error, error) {
 	trimmed := strings.TrimSpace(command)
 	if trimmed == "" {
 		return nil, errors.New("command is empty")
 	}
 
-	parts := strings.Fields(trimmed)
-	name := parts[0]
-	baseArgs := append([]string(nil), parts[1:]...)
+	if script, ok := strings.CutPrefix(trimmed, "!"); ok {
+		if strings.TrimSpace(script) == "" {
+			return nil, errors.New("command is empty")
+		}
+		return shellCommand(script), nil
+	}
+
+	words, err := Split(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("parse command %q: %w", trimmed, err)
+	}
+
+	var env []string
+	for len(words) > 0 && envAssignment.MatchString(words[0]) {
+		env = append(env, words[0])
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("parse command %q: no program to run", trimmed)
+	}
+
+	name := words[0]
+	baseArgs := append([]string(nil), words[1:]...)
+	placesTarget := hasTargetPlaceholder(baseArgs...)
+
+	var lineArgs []string
+	if !placesTarget && !hasLinePlaceholder(baseArgs...) {
+		if template, ok := lines[filepath.Base(name)]; ok {
+			if lineArgs, err = Split(template); err != nil {
+				return nil, fmt.Errorf("line template for %s: %w", filepath.Base(name), err)
+			}
+		}
+	}
 
 	return func(ctx context.Context, target string) error {
-		args := append(append([]string(nil), baseArgs...), target)
+		vars := VarsFrom(ctx)
+		values := placeholderValues(vars, target)
+		files := append([]string{values["{path}"]}, vars.Files...)
+
+		args := make([]string, 0, len(baseArgs)+len(files))
+		for _, word := range baseArgs {
+			if len(vars.Files) > 0 && strings.Contains(word, "{path}") {
+				for _, file := range files {
+					values["{path}"] = file
+					if expanded, ok := expand(word, values); ok {
+						args = append(args, expanded)
+					}
+				}
+				continue
+			}
+			if expanded, ok := expand(word, values); ok {
+				args = append(args, expanded)
+			}
+		}
+		switch {
+		case placesTarget:
+		case len(vars.Files) > 0:
+			args = append(args, target)
+			args = append(args, vars.Files...)
+		case lineArgs != nil && values["{line}"] != "":
+			for _, word := range lineArgs {
+				if expanded, ok := expand(word, values); ok {
+					args = append(args, expanded)
+				}
+			}
+		default:
+			args = append(args, target)
+		}
+
 		cmd := exec.CommandContext(ctx, name, args...)
+		if len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -29,10 +176,161 @@ func Command(command string) (func(context.Context, string) error, error) {
 	}, nil
 }
 
+func shellCommand(script string) func(context.Context, string) error {
+	placesTarget := hasTargetPlaceholder(script)
+
+	return func(ctx context.Context, target string) error {
+		vars := VarsFrom(ctx)
+		values := placeholderValues(vars, target)
+		expanded := shellWord.ReplaceAllStringFunc(script, func(word string) string {
+			dropped := false
+			word = placeholder.ReplaceAllStringFunc(word, func(match string) string {
+				if values[match] == "" {
+					dropped = true
+				}
+				if match == "{path}" && len(vars.Files) > 0 {
+					quoted := []string{Quote(values[match])}
+					for _, file := range vars.Files {
+						quoted = append(quoted, Quote(file))
+					}
+					return strings.Join(quoted, " ")
+				}
+				return Quote(values[match])
+			})
+			if dropped {
+				return ""
+			}
+			return word
+		})
+
+		var args []string
+		if !placesTarget {
+			args = append([]string{target}, vars.Files...)
+		}
+		return Shell(ctx, expanded, args)
+	}
+}
+
+func placeholderValues(vars Vars, target string) map[string]string {
+	values := map[string]string{
+		"{path}": vars.Path,
+		"{key}":  vars.Key,
+		"{line}": "",
+		"{url}":  vars.URL,
+	}
+	if values["{path}"] == "" {
+		values["{path}"] = target
+	}
+	if values["{url}"] == "" {
+		values["{url}"] = target
+	}
+	if vars.Line > 0 {
+		values["{line}"] = strconv.Itoa(vars.Line)
+	}
+	return values
+}
+
+// expand fills the placeholders in word.
+// It reports false when a placeholder is empty and the word should be dropped.
+func expand(word string, values map[string]string) (string, bool) {
+	ok := true
+	expanded := placeholder.ReplaceAllStringFunc(word, func(match string) string {
+		if values[match] == "" {
+			ok = false
+		}
+		return values[match]
+	})
+	return expanded, ok
+}
+
+func hasTargetPlaceholder(words ...string) bool {
+	for _, word := range words {
+		if strings.Contains(word, "{path}") || strings.Contains(word, "{url}") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLinePlaceholder(words ...string) bool {
+	for _, word := range words {
+		if strings.Contains(word, "{line}") {
+			return true
+		}
+	}
+	return false
+}
+
+// Shell runs script with sh, passing args as positional parameters.
+// When args are given they are appended to the script as "$@".
+func Shell(ctx context.Context, script string, args []string) error {
+	if strings.TrimSpace(script) == "" {
+		return errors.New("command is empty")
+	}
+	if len(args) > 0 {
+		script += ` "$@"`
+	}
+	cmd := exec.CommandContext(ctx, "sh", append([]string{"-c", script, "sh"}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Pipe returns a callable that runs command with r as its standard input,
+// for programs such as pagers that read what they show. The command is
+// parsed like Command's, without a target; env is added to the
+// environment of the process.
+func Pipe(command string, env []string) func(context.Context, io.Reader) error {
+	trimmed := strings.TrimSpace(command)
+	if trimmed == "" {
+		return func(context.Context, io.Reader) error { return errors.New("command is empty") }
+	}
+
+	env = append([]string(nil), env...)
+	var name string
+	var args []string
+	if script, ok := strings.CutPrefix(trimmed, "!"); ok {
+		name, args = "sh", []string{"-c", script, "sh"}
+	} else {
+		words, err := Split(trimmed)
+		if err != nil {
+			err = fmt.Errorf("parse command %q: %w", trimmed, err)
+			return func(context.Context, io.Reader) error { return err }
+		}
+		for len(words) > 0 && envAssignment.MatchString(words[0]) {
+			env = append(env, words[0])
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			err := fmt.Errorf("parse command %q: no program to run", trimmed)
+			return func(context.Context, io.Reader) error { return err }
+		}
+		name = words[0]
+		for _, word := range words[1:] {
+			// There is no file, so words with placeholders are dropped.
+			if !placeholder.MatchString(word) {
+				args = append(args, word)
+			}
+		}
+	}
+
+	return func(ctx context.Context, r io.Reader) error {
+		cmd := exec.CommandContext(ctx, name, args...)
+		if len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		cmd.Stdin = r
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+}
+
 // Run wraps Command and returns a function even when parsing fails.
 // If the command string is invalid, the returned function always returns that error.
-func Run(command string) func(context.Context, string) error {
-	fn, err := Command(command)
+func Run(command string, lines map[string]string) func(context.Context, string) error {
+	fn, err := Command(command, lines)
 	if err != nil {
 		return func(context.Context, string) error { return err }
 	}
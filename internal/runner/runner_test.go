@@ -3,18 +3,21 @@ package runner
 import (
 	"context"
 	"errors"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func TestCommandEmpty(t *testing.T) {
-    if _, err := Command(" \t "); err == nil {
+    if _, err := Command(" \t ", nil); err == nil {
         t.Fatalf("expected error for empty command")
     }
 }
 
 func TestCommandInvokesProcess(t *testing.T) {
-	fn, err := Command("echo")
+	fn, err := Command("echo", nil)
 	if err != nil {
 		t.Fatalf("Command error = %v", err)
 	}
@@ -29,8 +32,199 @@ func TestCommandInvokesProcess(t *testing.T) {
 }
 
 func TestRunReturnsErrorClosureForEmpty(t *testing.T) {
-    fn := Run("  ")
+    fn := Run("  ", nil)
     if err := fn(context.Background(), "target"); err == nil {
         t.Fatalf("expected error from invalid command")
     }
 }
+
+func TestShellPassesArgs(t *testing.T) {
+	if err := Shell(context.Background(), `test runner =`, []string{"runner"}); err != nil {
+		t.Fatalf("Shell error = %v", err)
+	}
+	if err := Shell(context.Background(), `test runner =`, []string{"other"}); err == nil {
+		t.Fatalf("expected non-zero exit to surface as error")
+	}
+}
+
+// record returns a command template that writes its arguments to a file,
+// and a func reading them back.
+func record(t *testing.T, args string) (string, func() string) {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "args")
+	template := `sh -c 'printf "%s|" "$@" > "` + out + `"' sh ` + args
+	return template, func() string {
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("ReadFile error = %v", err)
+		}
+		return string(data)
+	}
+}
+
+func TestCommandAppendsTargetWithoutPlaceholders(t *testing.T) {
+	template, read := record(t, `--wait "two words"`)
+	fn, err := Command(template, nil)
+	if err != nil {
+		t.Fatalf("Command error = %v", err)
+	}
+	if err := fn(context.Background(), "/tmp/my file"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if got := read(); got != "--wait|two words|/tmp/my file|" {
+		t.Fatalf("args = %q", got)
+	}
+}
+
+func TestCommandFillsPlaceholders(t *testing.T) {
+	template, read := record(t, `+{line} -t {key} {path}`)
+	fn, err := Command(template, nil)
+	if err != nil {
+		t.Fatalf("Command error = %v", err)
+	}
+
+	ctx := WithVars(context.Background(), Vars{Path: "/vault/ops/deploy", Key: "ops/deploy", Line: 12})
+	if err := fn(ctx, "/vault/ops/deploy"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if got := read(); got != "+12|-t|ops/deploy|/vault/ops/deploy|" {
+		t.Fatalf("args = %q", got)
+	}
+
+	// Without a line, "+{line}" is dropped rather than passed empty.
+	ctx = WithVars(context.Background(), Vars{Key: "ops/deploy"})
+	if err := fn(ctx, "/vault/ops/deploy"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if got := read(); got != "-t|ops/deploy|/vault/ops/deploy|" {
+		t.Fatalf("args = %q", got)
+	}
+}
+
+func TestCommandSetsLeadingEnv(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	fn, err := Command(`WOW_RUNNER_TEST=yes sh -c 'printf %s "$WOW_RUNNER_TEST" > "` + out + `"'`, nil)
+	if err != nil {
+		t.Fatalf("Command error = %v", err)
+	}
+	if err := fn(context.Background(), "ignored"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "yes" {
+		t.Fatalf("env = %q, want yes", data)
+	}
+}
+
+func TestCommandShellMode(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	fn, err := Command(`!printf '%s|%s' {key} {path} > "` + out + `"`, nil)
+	if err != nil {
+		t.Fatalf("Command error = %v", err)
+	}
+	ctx := WithVars(context.Background(), Vars{Key: "it's here"})
+	if err := fn(ctx, "/tmp/target"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "it's here|/tmp/target" {
+		t.Fatalf("output = %q", data)
+	}
+}
+
+func TestCommandShellModeDropsEmptyPlaceholders(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	fn, err := Command(`!printf '%s|' +{line} {path} > "`+out+`"`, nil)
+	if err != nil {
+		t.Fatalf("Command error = %v", err)
+	}
+
+	ctx := WithVars(context.Background(), Vars{Line: 12})
+	if err := fn(ctx, "/tmp/target"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "+12|/tmp/target|" {
+		t.Fatalf("output = %q", data)
+	}
+
+	// Without a line, "+{line}" is dropped rather than passed as "+''".
+	if err := fn(context.Background(), "/tmp/target"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "/tmp/target|" {
+		t.Fatalf("output = %q", data)
+	}
+}
+
+func TestCommandRejectsBadTemplates(t *testing.T) {
+	for _, in := range []string{`vim 'oops`, `FOO=bar`, `!  `} {
+		if _, err := Command(in, nil); err == nil {
+			t.Fatalf("Command(%q) expected error", in)
+		}
+	}
+}
+
+func TestCommandUsesLineTemplate(t *testing.T) {
+	lines := map[string]string{"sh": "-g {path}:{line}"}
+
+	template, read := record(t, `--wait`)
+	fn, err := Command(template, lines)
+	if err != nil {
+		t.Fatalf("Command error = %v", err)
+	}
+
+	ctx := WithVars(context.Background(), Vars{Path: "/vault/ops/deploy", Line: 120})
+	if err := fn(ctx, "/vault/ops/deploy"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if got := read(); got != "--wait|-g|/vault/ops/deploy:120|" {
+		t.Fatalf("args = %q", got)
+	}
+
+	// Without a line, the target is appended as usual.
+	if err := fn(context.Background(), "/vault/ops/deploy"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if got := read(); got != "--wait|/vault/ops/deploy|" {
+		t.Fatalf("args = %q", got)
+	}
+}
+
+func TestCommandOpensSeveralFiles(t *testing.T) {
+	template, read := record(t, `--wait`)
+	fn, err := Command(template, nil)
+	if err != nil {
+		t.Fatalf("Command error = %v", err)
+	}
+	ctx := WithVars(context.Background(), Vars{Line: 3, Files: []string{"/b", "/c"}})
+	if err := fn(ctx, "/a"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if got := read(); got != "--wait|/a|/b|/c|" {
+		t.Fatalf("args = %q", got)
+	}
+
+	template, read = record(t, `--file={path}`)
+	if fn, err = Command(template, nil); err != nil {
+		t.Fatalf("Command error = %v", err)
+	}
+	if err := fn(ctx, "/a"); err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+	if got := read(); got != "--file=/a|--file=/b|--file=/c|" {
+		t.Fatalf("args = %q", got)
+	}
+}
+
+func TestPipeFeedsStdin(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	fn := Pipe(`sh -c 'cat > "`+out+`"; printf %s "$WOW_PIPE_TEST" >> "`+out+`"' {path}`, []string{"WOW_PIPE_TEST=env"})
+	if err := fn(context.Background(), strings.NewReader("hello\n")); err != nil {
+		t.Fatalf("Pipe error = %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "hello\nenv" {
+		t.Fatalf("output = %q", data)
+	}
+
+	if err := Pipe(`less 'oops`, nil)(context.Background(), strings.NewReader("")); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnterminatedQuote is returned by Split for a quote left open.
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// Split breaks s into words the way a POSIX shell would,
+// honouring single quotes, double quotes and backslash escapes.
+// It does not expand variables, globs or command substitutions.
+func Split(s string) ([]string, error) {
+	var (
+		words   []string
+		current strings.Builder
+		inWord  bool
+	)
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+
+		case r == '\\':
+			inWord = true
+			if i+1 < len(runes) {
+				i++
+				// A backslash-newline is a line continuation.
+				if runes[i] != '\n' {
+					current.WriteRune(runes[i])
+				}
+			}
+
+		case r == '\'':
+			inWord = true
+			end := indexRune(runes, i+1, '\'')
+			if end == -1 {
+				return nil, ErrUnterminatedQuote
+			}
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+
+		case r == '"':
+			inWord = true
+			closed := false
+			for i++; i < len(runes); i++ {
+				c := runes[i]
+				if c == '"' {
+					closed = true
+					break
+				}
+				// Inside double quotes a backslash only escapes these.
+				if c == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] != '\n' {
+						current.WriteRune(runes[i])
+					}
+					continue
+				}
+				current.WriteRune(c)
+			}
+			if !closed {
+				return nil, ErrUnterminatedQuote
+			}
+
+		default:
+			inWord = true
+			current.WriteRune(r)
+		}
+	}
+
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+func indexRune(runes []rune, from int, target rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// Quote returns s quoted for safe use as one word in a POSIX shell script.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"code --wait", []string{"code", "--wait"}},
+		{"  vim\t-p  ", []string{"vim", "-p"}},
+		{`"/Applications/My Editor.app/bin/edit" -w`, []string{"/Applications/My Editor.app/bin/edit", "-w"}},
+		{`open -a 'Google Chrome'`, []string{"open", "-a", "Google Chrome"}},
+		{`echo a\ b "c \"d\" \e"`, []string{"echo", "a b", `c "d" \e`}},
+		{`EDITOR_THEME=dark nvim`, []string{"EDITOR_THEME=dark", "nvim"}},
+		{`say ''`, []string{"say", ""}},
+		{`a'b'"c"d`, []string{"abcd"}},
+	}
+	for _, tc := range cases {
+		got, err := Split(tc.in)
+		if err != nil {
+			t.Fatalf("Split(%q) error = %v", tc.in, err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("Split(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSplitUnterminatedQuote(t *testing.T) {
+	for _, in := range []string{`vim 'oops`, `vim "oops`} {
+		if _, err := Split(in); !errors.Is(err, ErrUnterminatedQuote) {
+			t.Fatalf("Split(%q) error = %v, want ErrUnterminatedQuote", in, err)
+		}
+	}
+}
+
+func TestQuoteRoundTrips(t *testing.T) {
+	for _, in := range []string{"", "plain", "it's", "a b", `"$HOME"`} {
+		got, err := Split(Quote(in))
+		if err != nil {
+			t.Fatalf("Split(Quote(%q)) error = %v", in, err)
+		}
+		if len(got) != 1 || got[0] != in {
+			t.Fatalf("Split(Quote(%q)) = %q", in, got)
+		}
+	}
+}
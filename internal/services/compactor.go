@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// Compactor compresses stored snippets that are at least CompressAbove
+// bytes, such as those saved before compression was turned on.
+type Compactor struct {
+	BaseDir       string
+	DB            *sql.DB
+	CompressAbove int64
+}
+
+// CompactResult reports the space one snippet takes up on disk.
+type CompactResult struct {
+	Key    string
+	Before int64 // bytes on disk before compacting.
+	After  int64 // bytes on disk after, or that it would take with a dry run.
+}
+
+// Compact compresses every uncompressed snippet of at least
+// CompressAbove bytes. A snippet is only rewritten when that makes it
+// smaller, and only those are reported. With dryRun nothing is
+// written, but the results are the same.
+func (c *Compactor) Compact(ctx context.Context, dryRun bool) ([]CompactResult, error) {
+	if c.DB == nil {
+		return nil, errors.New("compactor misconfigured")
+	}
+	if c.CompressAbove <= 0 {
+		return nil, errors.New("compactor needs a size to compress above")
+	}
+
+	entries, err := storage.ListMetadata(ctx, c.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []CompactResult
+	for _, meta := range entries {
+		if meta.Encoding != storage.EncodingNone {
+			continue
+		}
+		res, ok, err := c.compact(ctx, meta, dryRun)
+		if err != nil {
+			return results, fmt.Errorf("compact %q: %w", meta.Key, err)
+		}
+		if ok {
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+func (c *Compactor) compact(ctx context.Context, meta model.Metadata, dryRun bool) (CompactResult, bool, error) {
+	path, err := key.ResolvePath(c.BaseDir, meta.Key)
+	if err != nil {
+		return CompactResult{}, false, err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return CompactResult{}, false, nil
+	}
+	if err != nil {
+		return CompactResult{}, false, fmt.Errorf("stat snippet file: %w", err)
+	}
+	if info.Size() < c.CompressAbove {
+		return CompactResult{}, false, nil
+	}
+
+	src, err := storage.Open(path, storage.EncodingNone)
+	if err != nil {
+		return CompactResult{}, false, err
+	}
+	defer src.Close()
+
+	pending, err := storage.Create(path)
+	if err != nil {
+		return CompactResult{}, false, err
+	}
+	defer pending.Discard()
+
+	if _, err := io.Copy(pending, src); err != nil {
+		return CompactResult{}, false, fmt.Errorf("write temp file: %w", err)
+	}
+	encoding, err := pending.Compress()
+	if err != nil {
+		return CompactResult{}, false, err
+	}
+	if encoding == storage.EncodingNone {
+		return CompactResult{}, false, nil
+	}
+	after, err := pending.Size()
+	if err != nil {
+		return CompactResult{}, false, err
+	}
+	res := CompactResult{Key: meta.Key, Before: info.Size(), After: after}
+	if dryRun {
+		return res, true, nil
+	}
+
+	// The encoding is recorded first, and put back if the file cannot
+	// be moved into place, so the two never disagree for long.
+	updated := meta
+	updated.Encoding = encoding
+	if updated.Size == 0 {
+		updated.Size = info.Size()
+	}
+	if err := storage.UpdateMetadata(ctx, c.DB, updated); err != nil {
+		return CompactResult{}, false, err
+	}
+	if err := pending.Commit(); err != nil {
+		_ = storage.UpdateMetadata(ctx, c.DB, meta)
+		return CompactResult{}, false, err
+	}
+	return res, true, nil
+}
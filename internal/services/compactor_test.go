@@ -0,0 +1,80 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func TestCompactorCompressesLargeSnippets(t *testing.T) {
+	s, ctx := newTestSaver(t)
+
+	logs := strings.Repeat("10.0.0.1 - - GET /index.html 200\n", 500)
+	for k, content := range map[string]string{
+		"logs/access": logs,
+		"notes/small": "remember the milk\n",
+	} {
+		if _, err := s.Save(ctx, SaveRequest{Key: k, Reader: strings.NewReader(content)}); err != nil {
+			t.Fatalf("Save(%s) error = %v", k, err)
+		}
+	}
+
+	c := &Compactor{BaseDir: s.BaseDir, DB: s.DB, CompressAbove: 1024}
+
+	preview, err := c.Compact(ctx, true)
+	if err != nil {
+		t.Fatalf("Compact dry run error = %v", err)
+	}
+	if len(preview) != 1 || preview[0].Key != "logs/access" {
+		t.Fatalf("dry run results = %+v, want logs/access only", preview)
+	}
+	if meta, _ := storage.GetMetadata(ctx, s.DB, "logs/access"); meta.Encoding != storage.EncodingNone {
+		t.Fatalf("dry run stored encoding %q", meta.Encoding)
+	}
+
+	results, err := c.Compact(ctx, false)
+	if err != nil {
+		t.Fatalf("Compact error = %v", err)
+	}
+	if len(results) != 1 || results[0] != preview[0] {
+		t.Fatalf("results = %+v, want %+v", results, preview)
+	}
+	if results[0].Before != int64(len(logs)) || results[0].After >= results[0].Before {
+		t.Fatalf("result = %+v, want it to shrink from %d bytes", results[0], len(logs))
+	}
+
+	path := filepath.Join(s.BaseDir, "logs", "access")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat error = %v", err)
+	}
+	if info.Size() != results[0].After {
+		t.Fatalf("file is %d bytes, want %d", info.Size(), results[0].After)
+	}
+
+	meta, err := storage.GetMetadata(ctx, s.DB, "logs/access")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if meta.Encoding != storage.EncodingGzip || meta.Size != int64(len(logs)) {
+		t.Fatalf("metadata = %+v, want gzip with the original size", meta)
+	}
+	data, err := storage.ReadEncoded(path, meta.Encoding)
+	if err != nil {
+		t.Fatalf("ReadEncoded error = %v", err)
+	}
+	if string(data) != logs {
+		t.Fatalf("content changed by compacting")
+	}
+
+	again, err := c.Compact(ctx, false)
+	if err != nil {
+		t.Fatalf("second Compact error = %v", err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("second Compact results = %+v, want none", again)
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/runner"
+	"github.com/llywelwyn/wow/internal/storage"
+	"github.com/llywelwyn/wow/internal/vault"
+)
+
+// ComposeRequest captures the inputs for writing a new snippet in the editor.
+type ComposeRequest struct {
+	Key         string
+	Description string
+	Tags        []string
+	From        string    // snippet key or template name to start from.
+	Tee         io.Writer // receives the snippet as it is saved; nil for none.
+	NoDupes     bool      // refuse content already saved under another key.
+	Encrypt     bool      // seal the snippet with the Saver's passphrase.
+	SkipScan    bool      // save without scanning for secrets.
+}
+
+// Composer writes new snippets in the user's editor.
+type Composer struct {
+	Saver     *Saver
+	Open      func(context.Context, string) error
+	Vaults    vault.Stack // where From snippets are looked up; defaults to the Saver's vault.
+	Templates string      // directory of named templates.
+}
+
+// Compose opens the editor on a scratch file and saves what is written.
+// It returns ErrEmptySnippet, saving nothing, when the buffer is left empty.
+// When saving fails the scratch file is kept for the user, unless its
+// text was to be encrypted; then it is shredded.
+func (c *Composer) Compose(ctx context.Context, req ComposeRequest) (SaveResult, error) {
+	if c.Saver == nil || c.Open == nil {
+		return SaveResult{}, errors.New("composer misconfigured")
+	}
+
+	name := "snippet"
+	if req.Key != "" {
+		normalized, err := key.Normalize(req.Key)
+		if err != nil {
+			return SaveResult{}, err
+		}
+		// Refuse up front rather than after the user has typed everything.
+		path, err := key.ResolvePath(c.Saver.BaseDir, normalized)
+		if err != nil {
+			return SaveResult{}, err
+		}
+		exists, err := storage.Exists(path)
+		if err != nil {
+			return SaveResult{}, err
+		}
+		if exists {
+			return SaveResult{}, fmt.Errorf("%w: %s", ErrSnippetExists, normalized)
+		}
+		req.Key = normalized
+		name = filepath.Base(path)
+	}
+
+	var seed []byte
+	if req.From != "" {
+		var err error
+		if seed, err = c.seed(ctx, req.From, req.Encrypt); err != nil {
+			return SaveResult{}, err
+		}
+	}
+
+	tmp, err := scratchFile(name, seed)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	keep, sealed := false, req.Encrypt
+	defer func() {
+		if !keep {
+			if sealed {
+				_ = storage.Shred(tmp)
+			}
+			_ = os.RemoveAll(filepath.Dir(tmp))
+		}
+	}()
+
+	if err := c.Open(runner.WithVars(ctx, runner.Vars{Path: tmp, Key: req.Key}), tmp); err != nil {
+		return SaveResult{}, err
+	}
+
+	data, err := storage.Read(tmp)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return SaveResult{}, ErrEmptySnippet
+	}
+	if s := c.Saver.Secrets; s != nil && s.Policy == SecretsEncrypt && !req.SkipScan && len(s.Scan(data)) > 0 {
+		sealed = true
+	}
+
+	res, err := c.Saver.Save(ctx, SaveRequest{
+		Key:         req.Key,
+		Description: req.Description,
+		Tags:        req.Tags,
+		Reader:      bytes.NewReader(data),
+		Tee:         req.Tee,
+		NoDupes:     req.NoDupes,
+		Encrypt:     req.Encrypt,
+		SkipScan:    req.SkipScan,
+	})
+	if err != nil {
+		// Plaintext meant to be encrypted is never left behind.
+		if sealed {
+			return SaveResult{}, fmt.Errorf("%w (your text was not kept, as it was to be encrypted)", err)
+		}
+		keep = true
+		return SaveResult{}, fmt.Errorf("%w (your text is in %s)", err, tmp)
+	}
+	return res, nil
+}
+
+// seed returns the content of the snippet named from, or else of the
+// template by that name. Encrypted snippets are only decrypted when the
+// new snippet will be encrypted too.
+func (c *Composer) seed(ctx context.Context, from string, encrypt bool) ([]byte, error) {
+	vaults := c.Vaults
+	if len(vaults) == 0 {
+		vaults = vault.Stack{{Name: "user", BaseDir: c.Saver.BaseDir, DB: c.Saver.DB}}
+	}
+
+	if _, _, err := vaults.Resolve(from); err == nil {
+		var passphrase storage.Passphrase
+		if encrypt {
+			passphrase = c.Saver.Passphrase
+		}
+		return vaults.Read(ctx, from, passphrase)
+	}
+
+	if c.Templates != "" && filepath.IsLocal(from) {
+		data, err := storage.Read(filepath.Join(c.Templates, from))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("--from %q: no snippet or template by that name", from)
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// ErrAlreadyEncrypted is returned when encrypting an encrypted snippet.
+var ErrAlreadyEncrypted = errors.New("snippet is already encrypted")
+
+// ErrNotEncrypted is returned when decrypting a snippet that is not encrypted.
+var ErrNotEncrypted = errors.New("snippet is not encrypted")
+
+// Crypter encrypts stored snippets in place, and decrypts them back.
+//
+// Only the file's encoding and hash change; the snippet keeps its
+// modified time, as its content stays the same.
+type Crypter struct {
+	BaseDir    string
+	DB         *sql.DB
+	Passphrase storage.Passphrase
+
+	// CompressAbove is the size from which decrypted content is
+	// gzipped, as for Saver; zero never compresses.
+	CompressAbove int64
+
+	// Force rewrites protected snippets too.
+	Force bool
+}
+
+// Encrypt seals the snippet's content with the passphrase.
+func (c *Crypter) Encrypt(ctx context.Context, rawKey string) (model.Metadata, error) {
+	return c.rewrite(ctx, rawKey, true)
+}
+
+// Decrypt stores the snippet's content as plaintext again.
+func (c *Crypter) Decrypt(ctx context.Context, rawKey string) (model.Metadata, error) {
+	return c.rewrite(ctx, rawKey, false)
+}
+
+func (c *Crypter) rewrite(ctx context.Context, rawKey string, encrypt bool) (model.Metadata, error) {
+	if c.DB == nil || c.Passphrase == nil {
+		return model.Metadata{}, errors.New("crypter misconfigured")
+	}
+
+	normalized, err := key.Normalize(rawKey)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	meta, err := storage.GetMetadata(ctx, c.DB, normalized)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	if err := refuseProtected(meta, c.Force); err != nil {
+		return model.Metadata{}, err
+	}
+	encrypted := meta.Encoding == storage.EncodingEncrypted
+	if encrypt && encrypted {
+		return model.Metadata{}, fmt.Errorf("%w: %s", ErrAlreadyEncrypted, normalized)
+	}
+	if !encrypt && !encrypted {
+		return model.Metadata{}, fmt.Errorf("%w: %s", ErrNotEncrypted, normalized)
+	}
+
+	path, err := key.ResolvePath(c.BaseDir, normalized)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	content, err := storage.ReadDecrypted(path, meta.Encoding, c.Passphrase)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	defer clear(content)
+
+	pending, err := storage.Create(path)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	defer pending.Discard()
+	// Either way the temp file holds the plaintext of a snippet that
+	// was, or is to be, encrypted.
+	pending.ShredOnDiscard()
+
+	if _, err := pending.Write(content); err != nil {
+		return model.Metadata{}, fmt.Errorf("write temp file: %w", err)
+	}
+
+	updated := meta
+	if encrypt {
+		pass, err := c.Passphrase()
+		if err != nil {
+			return model.Metadata{}, err
+		}
+		if err := pending.Encrypt(pass); err != nil {
+			return model.Metadata{}, err
+		}
+		updated.Encoding = storage.EncodingEncrypted
+		if updated.SHA256, err = pending.SHA256(); err != nil {
+			return model.Metadata{}, err
+		}
+	} else {
+		updated.Encoding = storage.EncodingNone
+		if c.CompressAbove > 0 && int64(len(content)) >= c.CompressAbove {
+			if updated.Encoding, err = pending.Compress(); err != nil {
+				return model.Metadata{}, err
+			}
+		}
+		digest := sha256.Sum256(content)
+		updated.SHA256 = hex.EncodeToString(digest[:])
+	}
+	updated.Size = int64(len(content))
+
+	// As when compacting, the metadata is updated first and put back
+	// if the file cannot be moved into place.
+	if err := storage.UpdateMetadata(ctx, c.DB, updated); err != nil {
+		return model.Metadata{}, err
+	}
+	if err := pending.Commit(); err != nil {
+		_ = storage.UpdateMetadata(ctx, c.DB, meta)
+		return model.Metadata{}, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func TestCrypterEncryptsAndDecryptsInPlace(t *testing.T) {
+	s, ctx := newTestSaver(t)
+	saved, err := s.Save(ctx, SaveRequest{Key: "db/prod", Reader: strings.NewReader("hunter2\n")})
+	if err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	c := &Crypter{BaseDir: s.BaseDir, DB: s.DB, Passphrase: func() ([]byte, error) { return []byte("pass"), nil }}
+	encrypted, err := c.Encrypt(ctx, "db/prod")
+	if err != nil {
+		t.Fatalf("Encrypt error = %v", err)
+	}
+	if encrypted.Encoding != storage.EncodingEncrypted || encrypted.SHA256 == saved.Metadata.SHA256 {
+		t.Fatalf("Encrypt metadata = %+v, want encrypted with a new hash", encrypted)
+	}
+	if encrypted.Size != saved.Metadata.Size || !encrypted.Modified.Equal(saved.Metadata.Modified) {
+		t.Fatalf("Encrypt metadata = %+v, want size and modified time kept", encrypted)
+	}
+	raw, err := os.ReadFile(filepath.Join(s.BaseDir, "db", "prod"))
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if strings.Contains(string(raw), "hunter2") {
+		t.Fatalf("snippet file holds plaintext: %q", raw)
+	}
+	if _, err := c.Encrypt(ctx, "db/prod"); !errors.Is(err, ErrAlreadyEncrypted) {
+		t.Fatalf("second Encrypt err = %v, want ErrAlreadyEncrypted", err)
+	}
+
+	decrypted, err := c.Decrypt(ctx, "db/prod")
+	if err != nil {
+		t.Fatalf("Decrypt error = %v", err)
+	}
+	if decrypted.Encoding != storage.EncodingNone || decrypted.SHA256 != saved.Metadata.SHA256 {
+		t.Fatalf("Decrypt metadata = %+v, want plain with the original hash", decrypted)
+	}
+	if _, err := c.Decrypt(ctx, "db/prod"); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("second Decrypt err = %v, want ErrNotEncrypted", err)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/hooks"
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// Deduper finds snippets saved more than once and folds them into one.
+type Deduper struct {
+	BaseDir string
+	DB      *sql.DB
+	Now     func() time.Time
+	Hooks   *hooks.Runner
+	Force   bool // merges protected snippets too.
+}
+
+// Groups returns every set of two or more snippets with identical
+// content. Each group is sorted by key, and groups by their first key.
+// Snippets whose content was never hashed are left out.
+func (d *Deduper) Groups(ctx context.Context) ([][]model.Metadata, error) {
+	if d.DB == nil {
+		return nil, errors.New("deduper misconfigured")
+	}
+
+	entries, err := storage.ListMetadata(ctx, d.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	bySum := make(map[string][]model.Metadata)
+	for _, meta := range entries {
+		if meta.SHA256 != "" {
+			bySum[meta.SHA256] = append(bySum[meta.SHA256], meta)
+		}
+	}
+
+	var groups [][]model.Metadata
+	for _, group := range bySum {
+		if len(group) < 2 {
+			continue
+		}
+		slices.SortFunc(group, func(a, b model.Metadata) int { return strings.Compare(a.Key, b.Key) })
+		groups = append(groups, group)
+	}
+	slices.SortFunc(groups, func(a, b []model.Metadata) int { return strings.Compare(a[0].Key, b[0].Key) })
+	return groups, nil
+}
+
+// Merge keeps the snippet keep and removes the others, which must have
+// the same content. Their tags are added to keep, as is the first
+// description found when keep has none.
+func (d *Deduper) Merge(ctx context.Context, keep string, others []string) (model.Metadata, error) {
+	if d.DB == nil || d.Now == nil {
+		return model.Metadata{}, errors.New("deduper misconfigured")
+	}
+
+	normalized, err := key.Normalize(keep)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	kept, err := storage.GetMetadata(ctx, d.DB, normalized)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	if err := refuseProtected(kept, d.Force); err != nil {
+		return model.Metadata{}, err
+	}
+	if kept.SHA256 == "" {
+		return model.Metadata{}, fmt.Errorf("%q has no recorded content hash", kept.Key)
+	}
+
+	merged := kept
+	var removed []string
+	for _, other := range others {
+		k, err := key.Normalize(other)
+		if err != nil {
+			return model.Metadata{}, err
+		}
+		if k == kept.Key {
+			continue
+		}
+		meta, err := storage.GetMetadata(ctx, d.DB, k)
+		if err != nil {
+			return model.Metadata{}, err
+		}
+		if meta.SHA256 != kept.SHA256 {
+			return model.Metadata{}, fmt.Errorf("%q does not have the same content as %q", meta.Key, kept.Key)
+		}
+		if err := refuseProtected(meta, d.Force); err != nil {
+			return model.Metadata{}, err
+		}
+		merged.Tags = MergeTags(merged.Tags, parseTags(meta.Tags), nil)
+		if merged.Description == "" {
+			merged.Description = meta.Description
+		}
+		removed = append(removed, meta.Key)
+	}
+
+	if merged.Tags != kept.Tags || merged.Description != kept.Description {
+		merged.Modified = d.Now().UTC()
+		if err := storage.UpdateMetadata(ctx, d.DB, merged); err != nil {
+			return model.Metadata{}, err
+		}
+	}
+
+	remover := &Remover{BaseDir: d.BaseDir, DB: d.DB, Hooks: d.Hooks, Force: d.Force}
+	for _, k := range removed {
+		if err := remover.Remove(ctx, k); err != nil {
+			return merged, fmt.Errorf("remove %q: %w", k, err)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func TestDeduperGroupsAndMerges(t *testing.T) {
+	s, ctx := newTestSaver(t)
+
+	seed := []struct {
+		key, content, desc string
+		tags               []string
+	}{
+		{"k8s/pods", "kubectl get pods -A\n", "", []string{"k8s"}},
+		{"kube/pods", "kubectl get pods -A\n", "all pods", []string{"kubectl", "k8s"}},
+		{"misc/pods", "kubectl get pods -A\n", "", nil},
+		{"notes/todo", "buy milk\n", "", nil},
+	}
+	for _, sn := range seed {
+		if _, err := s.Save(ctx, SaveRequest{Key: sn.key, Reader: strings.NewReader(sn.content), Description: sn.desc, Tags: sn.tags}); err != nil {
+			t.Fatalf("Save(%s) error = %v", sn.key, err)
+		}
+	}
+
+	d := &Deduper{BaseDir: s.BaseDir, DB: s.DB, Now: s.Now}
+	groups, err := d.Groups(ctx)
+	if err != nil {
+		t.Fatalf("Groups error = %v", err)
+	}
+	if len(groups) != 1 || len(groups[0]) != 3 || groups[0][0].Key != "k8s/pods" {
+		t.Fatalf("groups = %+v, want the three pods snippets", groups)
+	}
+
+	if _, err := d.Merge(ctx, "k8s/pods", []string{"notes/todo"}); err == nil {
+		t.Fatalf("expected error merging different content")
+	}
+
+	merged, err := d.Merge(ctx, "k8s/pods", []string{"kube/pods", "misc/pods"})
+	if err != nil {
+		t.Fatalf("Merge error = %v", err)
+	}
+	if merged.Tags != "k8s,kubectl" {
+		t.Fatalf("Tags = %q, want k8s,kubectl", merged.Tags)
+	}
+	if merged.Description != "all pods" {
+		t.Fatalf("Description = %q, want all pods", merged.Description)
+	}
+
+	for _, k := range []string{"kube/pods", "misc/pods"} {
+		if _, err := storage.GetMetadata(ctx, s.DB, k); !errors.Is(err, storage.ErrMetadataNotFound) {
+			t.Fatalf("%s should be removed, got %v", k, err)
+		}
+	}
+	if groups, _ := d.Groups(ctx); len(groups) != 0 {
+		t.Fatalf("groups after merge = %+v, want none", groups)
+	}
+}
+
+func TestDeduperMergeRefusesProtectedWithoutForce(t *testing.T) {
+	s, ctx := newTestSaver(t)
+	for _, sn := range []struct{ key, tag string }{{"runbook", "ops"}, {"copy", "scratch"}} {
+		if _, err := s.Save(ctx, SaveRequest{Key: sn.key, Reader: strings.NewReader("restart it\n"), Tags: []string{sn.tag}}); err != nil {
+			t.Fatalf("Save(%s) error = %v", sn.key, err)
+		}
+	}
+	p := &Protector{DB: s.DB}
+	if _, err := p.Protect(ctx, "runbook"); err != nil {
+		t.Fatalf("Protect error = %v", err)
+	}
+
+	d := &Deduper{BaseDir: s.BaseDir, DB: s.DB, Now: s.Now}
+	if _, err := d.Merge(ctx, "runbook", []string{"copy"}); !errors.Is(err, ErrProtected) {
+		t.Fatalf("Merge into protected err = %v, want ErrProtected", err)
+	}
+	if _, err := d.Merge(ctx, "copy", []string{"runbook"}); !errors.Is(err, ErrProtected) {
+		t.Fatalf("Merge away protected err = %v, want ErrProtected", err)
+	}
+	if meta, _ := storage.GetMetadata(ctx, s.DB, "runbook"); meta.Tags != "ops" {
+		t.Fatalf("refused Merge changed tags to %q", meta.Tags)
+	}
+
+	d.Force = true
+	merged, err := d.Merge(ctx, "runbook", []string{"copy"})
+	if err != nil {
+		t.Fatalf("forced Merge error = %v", err)
+	}
+	if merged.Tags != "ops,scratch" || !merged.Protected {
+		t.Fatalf("forced Merge = %+v, want protected with both tags", merged)
+	}
+}
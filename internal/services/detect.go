@@ -2,15 +2,324 @@ package services
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
 	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
 )
 
-func detectType(payload []byte) string {
-	first := firstLine(payload)
-	if isURL(first) {
-		return "url"
+// Detection is what a detector recognised in a snippet.
+type Detection struct {
+	Type     string // e.g. "url", "script", "code" or "text".
+	Language string // e.g. "go" or "bash"; empty when unknown.
+	MIME     string // media type, e.g. "image/png"; filled in by Detect.
+}
+
+// Detector recognises one kind of snippet from its key and content.
+// It reports false when the snippet is not of its kind.
+type Detector func(key string, payload []byte) (Detection, bool)
+
+// Detectors are tried in order by Detect, and the first to recognise
+// a snippet wins. Content that is certain, such as binary data or a
+// shebang, comes before the key's extension, which comes before
+// guesses from content. Entries may be added at startup.
+var Detectors = []Detector{
+	detectBinary,
+	detectURL,
+	detectShebang,
+	detectExtension,
+	detectDiff,
+	detectJSON,
+	detectSQL,
+	detectCode,
+	detectMarkdown,
+	detectYAML,
+}
+
+// Detect works out the type, language and media type of a snippet.
+// Snippets no detector recognises are plain text.
+func Detect(key string, payload []byte) Detection {
+	found := Detection{Type: "text"}
+	for _, detect := range Detectors {
+		if d, ok := detect(key, payload); ok {
+			found = d
+			break
+		}
+	}
+	if found.MIME == "" {
+		found.MIME = detectMIME(key, payload, found.Type == "binary")
+	}
+	return found
+}
+
+// detectMIME sniffs the media type of payload. The key's extension
+// is used when the content alone says too little.
+func detectMIME(key string, payload []byte, binary bool) string {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(payload))
+	if sniffed != "" && sniffed != "application/octet-stream" && sniffed != "text/plain" {
+		return sniffed
+	}
+	if byExt, _, _ := mime.ParseMediaType(mime.TypeByExtension(path.Ext(key))); byExt != "" {
+		return byExt
+	}
+	if binary {
+		return "application/octet-stream"
+	}
+	return "text/plain"
+}
+
+// sniffLen bounds how much of a snippet detectors look at. Saver gives
+// Detect no more than this, so a payload this long may be cut short.
+const sniffLen = 8000
+
+func detectBinary(_ string, payload []byte) (Detection, bool) {
+	head := payload
+	if len(head) >= sniffLen {
+		head = withoutPartialRune(head[:sniffLen])
+	}
+	if bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(head) {
+		return Detection{Type: "binary"}, true
+	}
+	return Detection{}, false
+}
+
+// withoutPartialRune drops a rune cut in two at the end of b.
+func withoutPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i > len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+func detectURL(_ string, payload []byte) (Detection, bool) {
+	if isURL(firstLine(payload)) {
+		return Detection{Type: "url"}, true
+	}
+	return Detection{}, false
+}
+
+// interpreters maps shebang programs to language names.
+var interpreters = map[string]string{
+	"sh":        "shell",
+	"bash":      "bash",
+	"zsh":       "zsh",
+	"fish":      "fish",
+	"python":    "python",
+	"node":      "javascript",
+	"deno":      "typescript",
+	"ruby":      "ruby",
+	"perl":      "perl",
+	"php":       "php",
+	"lua":       "lua",
+	"awk":       "awk",
+	"tclsh":     "tcl",
+	"pwsh":      "powershell",
+	"osascript": "applescript",
+}
+
+func detectShebang(_ string, payload []byte) (Detection, bool) {
+	line, ok := strings.CutPrefix(firstLine(payload), "#!")
+	if !ok {
+		return Detection{}, false
+	}
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return Detection{Type: "script"}, true
+	}
+	program := path.Base(words[0])
+	if program == "env" {
+		program = ""
+		for _, word := range words[1:] {
+			if !strings.HasPrefix(word, "-") && !strings.Contains(word, "=") {
+				program = path.Base(word)
+				break
+			}
+		}
+	}
+	// python3.12 is python.
+	program = strings.TrimRight(program, "0123456789.")
+	language, ok := interpreters[program]
+	if !ok {
+		language = program
+	}
+	return Detection{Type: "script", Language: language}, true
+}
+
+// languages maps file extensions in keys to language names.
+var languages = map[string]string{
+	".bash":  "bash",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".diff":  "diff",
+	".patch": "diff",
+	".go":    "go",
+	".html":  "html",
+	".java":  "java",
+	".js":    "javascript",
+	".json":  "json",
+	".kt":    "kotlin",
+	".lua":   "lua",
+	".md":    "markdown",
+	".php":   "php",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".sh":    "shell",
+	".sql":   "sql",
+	".swift": "swift",
+	".toml":  "toml",
+	".ts":    "typescript",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".zsh":   "zsh",
+}
+
+// languageTypes are the types of languages that are not code.
+var languageTypes = map[string]string{
+	"bash":     "script",
+	"shell":    "script",
+	"zsh":      "script",
+	"diff":     "diff",
+	"json":     "json",
+	"markdown": "markdown",
+	"sql":      "sql",
+	"toml":     "toml",
+	"yaml":     "yaml",
+}
+
+// detectLanguage guesses a snippet's language from the extension of its key.
+// It returns "" when the extension is missing or unknown.
+func detectLanguage(key string) string {
+	return languages[strings.ToLower(path.Ext(key))]
+}
+
+func detectExtension(key string, _ []byte) (Detection, bool) {
+	language := detectLanguage(key)
+	if language == "" {
+		return Detection{}, false
+	}
+	typ, ok := languageTypes[language]
+	if !ok {
+		typ = "code"
+	}
+	return Detection{Type: typ, Language: language}, true
+}
+
+var diffHeader = regexp.MustCompile(`(?m)^(diff --git |--- \S.*\n\+\+\+ \S)`)
+
+func detectDiff(_ string, payload []byte) (Detection, bool) {
+	if diffHeader.Match(payload) && bytes.Contains(payload, []byte("\n@@ ")) {
+		return Detection{Type: "diff", Language: "diff"}, true
+	}
+	return Detection{}, false
+}
+
+func detectJSON(_ string, payload []byte) (Detection, bool) {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return Detection{}, false
+	}
+	if !json.Valid(trimmed) && !(len(payload) >= sniffLen && jsonPrefix(trimmed)) {
+		return Detection{}, false
+	}
+	return Detection{Type: "json", Language: "json"}, true
+}
+
+// jsonPrefix reports whether data is JSON that may have been cut short.
+func jsonPrefix(data []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err != nil {
+			return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+var sqlStatement = regexp.MustCompile(`(?is)^\s*(select\s.+\sfrom\s|insert\s+into\s|update\s+\S+\s+set\s|delete\s+from\s|(create|alter|drop)\s+(or\s+replace\s+)?(unique\s+)?(table|index|view|trigger|schema|database|function)\s|with\s+\w+\s+as\s*\()`)
+
+func detectSQL(_ string, payload []byte) (Detection, bool) {
+	if sqlStatement.Match(payload) {
+		return Detection{Type: "sql", Language: "sql"}, true
+	}
+	return Detection{}, false
+}
+
+// codeHints recognise languages from lines typical of their source.
+// They are tried in order, so stricter hints come first.
+var codeHints = []struct {
+	language string
+	pattern  *regexp.Regexp
+}{
+	{"php", regexp.MustCompile(`^\s*<\?php`)},
+	{"html", regexp.MustCompile(`(?i)^\s*<(!doctype html|html)[\s>]`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$`)},
+	{"c", regexp.MustCompile(`(?m)^#include\s*[<"]`)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+.*\{\s*$|^use \w+::`)},
+	{"java", regexp.MustCompile(`(?m)^\s*public (final |abstract )?(class|interface) \w+`)},
+	{"python", regexp.MustCompile(`(?m)^(def \w+\(.*\):|class \w+(\(.*\))?:|from [\w.]+ import |import \w+$)`)},
+	{"javascript", regexp.MustCompile(`(?m)^(const|let|var) \w+ = |^function \w+\(|^export (default |const |function )|require\(['"]`)},
+	{"ruby", regexp.MustCompile(`(?m)^(require ['"]|def \w+[^:]*$|module [A-Z])`)},
+}
+
+func detectCode(_ string, payload []byte) (Detection, bool) {
+	for _, hint := range codeHints {
+		if hint.pattern.Match(payload) {
+			return Detection{Type: "code", Language: hint.language}, true
+		}
+	}
+	return Detection{}, false
+}
+
+var markdownHints = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^#{1,6} \S`),
+	regexp.MustCompile("(?m)^```"),
+	regexp.MustCompile(`(?m)^\s*([-*+]|\d+\.) \S`),
+	regexp.MustCompile(`\[[^\]]+\]\([^)\s]+\)`),
+	regexp.MustCompile(`(\*\*|__)\S[^*_]*\S(\*\*|__)`),
+	regexp.MustCompile(`(?m)^> \S`),
+}
+
+// detectMarkdown takes two kinds of Markdown syntax as proof, as a lone
+// "# " line is as likely to be a comment.
+func detectMarkdown(_ string, payload []byte) (Detection, bool) {
+	found := 0
+	for _, hint := range markdownHints {
+		if hint.Match(payload) {
+			found++
+		}
+	}
+	if found < 2 {
+		return Detection{}, false
+	}
+	return Detection{Type: "markdown", Language: "markdown"}, true
+}
+
+// detectYAML takes a document starting with "---", or a mapping of at
+// least two keys, as YAML. Prose with one colon is left alone.
+func detectYAML(_ string, payload []byte) (Detection, bool) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(payload, &doc); err != nil {
+		return Detection{}, false
+	}
+	if len(doc) < 2 && !bytes.HasPrefix(payload, []byte("---\n")) {
+		return Detection{}, false
 	}
-	return "text"
+	return Detection{Type: "yaml", Language: "yaml"}, true
 }
 
 func firstLine(payload []byte) string {
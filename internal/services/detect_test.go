@@ -0,0 +1,82 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		payload string
+		want    Detection
+	}{
+		{"plain text", "notes/todo", "buy milk\n", Detection{Type: "text"}},
+		{"url", "links/site", "https://example.com\n", Detection{Type: "url"}},
+		{"shebang", "ops/deploy", "#!/bin/bash\necho hi\n", Detection{Type: "script", Language: "bash"}},
+		{"env shebang", "ops/tool", "#!/usr/bin/env -S python3 -u\nprint(1)\n", Detection{Type: "script", Language: "python"}},
+		{"extension", "snips/main.go", "hello\n", Detection{Type: "code", Language: "go"}},
+		{"shell extension", "ops/run.sh", "echo hi\n", Detection{Type: "script", Language: "shell"}},
+		{"json", "data/user", `{"name": "wow", "tags": [1, 2]}`, Detection{Type: "json", Language: "json"}},
+		{"not json", "notes/braces", "{ not json }", Detection{Type: "text"}},
+		{"yaml", "k8s/pod", "apiVersion: v1\nkind: Pod\n", Detection{Type: "yaml", Language: "yaml"}},
+		{"one colon is prose", "notes/reminder", "Note: call mum\n", Detection{Type: "text"}},
+		{"markdown", "notes/readme", "# Title\n\n- one\n- two\n", Detection{Type: "markdown", Language: "markdown"}},
+		{"comment is not markdown", "notes/hash", "# just a comment\n", Detection{Type: "text"}},
+		{"sql", "db/users", "SELECT id, name\nFROM users\nWHERE id = 1;\n", Detection{Type: "sql", Language: "sql"}},
+		{"prose is not sql", "notes/chores", "Update the readme as needed\n", Detection{Type: "text"}},
+		{"diff", "patches/fix", "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n", Detection{Type: "diff", Language: "diff"}},
+		{"go source", "snips/server", "package main\n\nfunc main() {}\n", Detection{Type: "code", Language: "go"}},
+		{"python source", "snips/fib", "def fib(n):\n    return n\n", Detection{Type: "code", Language: "python"}},
+		{"binary", "bin/blob", "\x00\x01\x02", Detection{Type: "binary"}},
+		{"invalid utf-8", "bin/latin1", "caf\xe9", Detection{Type: "binary"}},
+	}
+	for _, tc := range tests {
+		got := Detect(tc.key, []byte(tc.payload))
+		if got.Type != tc.want.Type || got.Language != tc.want.Language {
+			t.Errorf("%s: Detect = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDetectMIME(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		payload string
+		want    string
+	}{
+		{"png", "img/logo", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"pdf", "docs/spec", "%PDF-1.7\n\x00\xff", "application/pdf"},
+		{"gzip", "backups/db", "\x1f\x8b\x08\x00\x00\x00", "application/x-gzip"},
+		{"unknown binary", "bin/blob", "\x00\x01\x02", "application/octet-stream"},
+		{"text", "notes/todo", "buy milk\n", "text/plain"},
+	}
+	for _, tc := range tests {
+		if got := Detect(tc.key, []byte(tc.payload)); got.MIME != tc.want {
+			t.Errorf("%s: MIME = %q, want %q", tc.name, got.MIME, tc.want)
+		}
+	}
+}
+
+func TestDetectBinaryIgnoresRuneCutAtLimit(t *testing.T) {
+	payload := append(bytes.Repeat([]byte("a"), sniffLen-1), []byte("é and more")...)
+	if got := Detect("notes/long", payload); got.Type == "binary" {
+		t.Fatalf("Detect = %+v, want text", got)
+	}
+}
+
+func TestDetectorsCanBeExtended(t *testing.T) {
+	defer func(saved []Detector) { Detectors = saved }(Detectors)
+	Detectors = append([]Detector{func(key string, _ []byte) (Detection, bool) {
+		return Detection{Type: "recipe"}, key == "food/soup"
+	}}, Detectors...)
+
+	if got := Detect("food/soup", []byte("package main\n")); got.Type != "recipe" {
+		t.Fatalf("Detect = %+v, want recipe", got)
+	}
+	if got := Detect("food/other", []byte("package main\n")); got.Language != "go" {
+		t.Fatalf("Detect = %+v, want go", got)
+	}
+}
@@ -1,76 +1,593 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/llywelwyn/wow/internal/hooks"
 	"github.com/llywelwyn/wow/internal/key"
 	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/runner"
 	"github.com/llywelwyn/wow/internal/storage"
 )
 
+// ErrEditAborted is returned when an edit conflict is resolved by aborting.
+var ErrEditAborted = errors.New("edit aborted")
+
+// Resolution is the choice made for an edit conflict.
+type Resolution int
+
+const (
+	// ResolveAbort keeps the snippet as it is on disk.
+	ResolveAbort Resolution = iota
+	// ResolveOverwrite replaces the snippet with the edited copy.
+	ResolveOverwrite
+	// ResolveMerge reopens the editor with both versions marked up.
+	ResolveMerge
+)
+
+// Conflict describes a snippet rewritten by someone else during an edit.
+type Conflict struct {
+	Key    string
+	Edited []byte // the editor's copy.
+	OnDisk []byte // what the snippet holds now.
+}
+
+// EditMode selects what an edit covers.
+type EditMode int
+
+const (
+	// EditContent edits the snippet's content.
+	EditContent EditMode = iota
+	// EditMeta edits the metadata as a YAML front-matter block.
+	EditMeta
+	// EditAll edits the front-matter followed by the content.
+	EditAll
+)
+
+// EditOptions controls an edit.
+type EditOptions struct {
+	Mode  EditMode
+	At    Position // where to place the cursor; not valid with EditMeta.
+	Force bool     // edits protected snippets too.
+}
+
+// EditResult reports the outcome of editing one snippet of a batch.
+type EditResult struct {
+	Key      string
+	Metadata model.Metadata
+	Changed  bool
+	Err      error
+}
+
 // Editor orchestrates edit operations for existing snippets.
+//
+// The editor works on temporary copies. When a copy is saved, its hash
+// is compared with the original's, so only real content changes count.
+// If the snippet itself changed while the editor was open, Resolve
+// decides what happens; without Resolve the edit is aborted.
+//
+// Front-matter that fails to parse is handed back to the editor with
+// the error written above it as a comment.
+//
+// Encrypted snippets are decrypted into the copy, which is shredded
+// afterwards, and sealed again with the same passphrase when saved.
 type Editor struct {
 	BaseDir string
 	DB      *sql.DB
 	Now     func() time.Time
 	Open    func(context.Context, string) error
+	Resolve func(context.Context, Conflict) (Resolution, error)
+	Hooks   *hooks.Runner
+
+	// CompressAbove is the size from which saved content is gzipped,
+	// as for Saver; zero never compresses.
+	CompressAbove int64
+
+	// Passphrase supplies the passphrase for encrypted snippets.
+	Passphrase storage.Passphrase
+
+	// Secrets scans edited content of snippets that are not encrypted,
+	// as Saver does; nil skips scanning. A blocked edit keeps its copy.
+	Secrets *SecretScanner
+	// Warn is told what was found when the policy lets an edit through.
+	Warn func(key string, findings []SecretFinding)
 }
 
 // Edit opens the snippet for modification and refreshes metadata when changed.
-func (e *Editor) Edit(ctx context.Context, rawKey string) (model.Metadata, error) {
+func (e *Editor) Edit(ctx context.Context, rawKey string, opts EditOptions) (model.Metadata, error) {
 	if e.DB == nil || e.Now == nil || e.Open == nil {
 		return model.Metadata{}, errors.New("editor misconfigured")
 	}
 
-	normalized, err := key.Normalize(rawKey)
+	dir, err := os.MkdirTemp("", "wow-edit-")
 	if err != nil {
+		return model.Metadata{}, fmt.Errorf("create edit dir: %w", err)
+	}
+	var s *editSession
+	keep := false
+	defer func() { removeScratch(dir, keep, s) }()
+
+	s, err = e.begin(ctx, dir, rawKey, opts)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	if err := e.Open(runner.WithVars(ctx, s.vars()), s.tmp); err != nil {
 		return model.Metadata{}, err
 	}
 
+	res := e.settle(ctx, s)
+	keep = s.kept
+	return res.Metadata, res.Err
+}
+
+// EditMany opens every snippet matched by patterns in one editor session,
+// then checks and saves each independently. A pattern is a key, a
+// prefix ending in "/", or a glob such as "k8s/*".
+//
+// Errors that stop the session from starting are returned directly;
+// problems with single snippets are reported in their result.
+func (e *Editor) EditMany(ctx context.Context, patterns []string, opts EditOptions) ([]EditResult, error) {
+	if e.DB == nil || e.Now == nil || e.Open == nil {
+		return nil, errors.New("editor misconfigured")
+	}
+
+	keys, err := e.expand(ctx, patterns)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 1 && opts.At != (Position{}) {
+		return nil, errors.New("a line or search applies to a single snippet")
+	}
+
+	dir, err := os.MkdirTemp("", "wow-edit-")
+	if err != nil {
+		return nil, fmt.Errorf("create edit dir: %w", err)
+	}
+	sessions := make([]*editSession, 0, len(keys))
+	keep := false
+	defer func() { removeScratch(dir, keep, sessions...) }()
+
+	for _, k := range keys {
+		s, err := e.begin(ctx, dir, k, opts)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	vars := sessions[0].vars()
+	for _, s := range sessions[1:] {
+		vars.Files = append(vars.Files, s.tmp)
+	}
+	if err := e.Open(runner.WithVars(ctx, vars), sessions[0].tmp); err != nil {
+		return nil, err
+	}
+
+	results := make([]EditResult, 0, len(sessions))
+	for _, s := range sessions {
+		results = append(results, e.settle(ctx, s))
+		keep = keep || s.kept
+	}
+	return results, nil
+}
+
+// expand turns patterns into existing keys, in order and without repeats.
+func (e *Editor) expand(ctx context.Context, patterns []string) ([]string, error) {
+	var all []model.Metadata
+	seen := make(map[string]bool)
+	var keys []string
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") && !strings.HasSuffix(pattern, "/") {
+			normalized, err := key.Normalize(pattern)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[normalized] {
+				seen[normalized] = true
+				keys = append(keys, normalized)
+			}
+			continue
+		}
+
+		if all == nil {
+			var err error
+			if all, err = storage.ListMetadata(ctx, e.DB); err != nil {
+				return nil, err
+			}
+			slices.SortFunc(all, func(a, b model.Metadata) int { return strings.Compare(a.Key, b.Key) })
+		}
+
+		matched := false
+		for _, meta := range all {
+			ok := strings.HasPrefix(meta.Key, pattern)
+			if !strings.HasSuffix(pattern, "/") {
+				var err error
+				if ok, err = path.Match(pattern, meta.Key); err != nil {
+					return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+				}
+			}
+			if !ok {
+				continue
+			}
+			matched = true
+			if !seen[meta.Key] {
+				seen[meta.Key] = true
+				keys = append(keys, meta.Key)
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("%w: nothing matches %q", storage.ErrMetadataNotFound, pattern)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no snippets to edit")
+	}
+	return keys, nil
+}
+
+// editSession tracks one snippet being edited through a scratch copy.
+type editSession struct {
+	key         string
+	path        string // the snippet file.
+	tmp         string // the copy given to the editor.
+	line        int
+	withHeader  bool
+	withContent bool
+
+	meta     model.Metadata // as stored.
+	updated  model.Metadata // with front-matter changes applied.
+	original []byte         // stored content the edit started from.
+	content  []byte         // edited content.
+
+	base      [sha256.Size]byte // hash of the content the edit is based on.
+	unchanged [sha256.Size]byte // hash of the copy as last written.
+	parseErr  error
+	merged    bool
+	kept      bool // the copy must outlive the session.
+}
+
+// editState is what review found in a scratch copy.
+type editState int
+
+const (
+	editUnchanged editState = iota
+	editReady
+	editReopen
+)
+
+// begin loads the snippet, runs the pre-edit hook and writes its copy under dir.
+func (e *Editor) begin(ctx context.Context, dir, rawKey string, opts EditOptions) (*editSession, error) {
+	normalized, err := key.Normalize(rawKey)
+	if err != nil {
+		return nil, err
+	}
+
 	meta, err := storage.GetMetadata(ctx, e.DB, normalized)
 	if err != nil {
-		return model.Metadata{}, err
+		return nil, err
+	}
+	if err := refuseProtected(meta, opts.Force); err != nil {
+		return nil, err
 	}
 
-	path, err := key.ResolvePath(e.BaseDir, normalized)
+	snippet, err := key.ResolvePath(e.BaseDir, normalized)
 	if err != nil {
-		return model.Metadata{}, err
+		return nil, err
 	}
 
-	before, err := os.Stat(path)
+	original, err := storage.ReadDecrypted(snippet, meta.Encoding, e.Passphrase)
 	if err != nil {
-		return model.Metadata{}, err
+		return nil, err
 	}
 
-	if err := e.Open(ctx, path); err != nil {
-		return model.Metadata{}, err
+	s := &editSession{
+		key:         normalized,
+		path:        snippet,
+		withHeader:  opts.Mode != EditContent,
+		withContent: opts.Mode != EditMeta,
+		meta:        meta,
+		updated:     meta,
+		original:    original,
+		content:     original,
+		base:        sha256.Sum256(original),
 	}
 
-	after, err := os.Stat(path)
+	if s.line, err = opts.At.lineIn(original); err != nil {
+		return nil, err
+	}
+	if s.line > 0 && !s.withContent {
+		return nil, errors.New("a line cannot be given when editing metadata only")
+	}
+
+	if err := e.Hooks.Run(ctx, hooks.NewPayload(hooks.PreEdit, snippet, meta)); err != nil {
+		return nil, err
+	}
+
+	buffer, err := s.render(meta, original)
 	if err != nil {
-		return model.Metadata{}, err
+		return nil, err
+	}
+	if s.line > 0 && s.withHeader {
+		s.line += bytes.Count(buffer[:len(buffer)-len(original)], []byte("\n"))
 	}
 
-	changed := after.ModTime() != before.ModTime() || after.Size() != before.Size()
-	if !changed {
-		return meta, nil
+	// The copy keeps the key's path so editors can pick a syntax and
+	// tell several copies apart.
+	s.tmp = filepath.Join(dir, filepath.FromSlash(normalized))
+	if opts.Mode == EditMeta {
+		s.tmp += ".yaml"
 	}
+	if err := s.write(buffer); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
 
-	data, err := storage.Read(path)
+// keepCopy keeps the copy past the session and says where it is. Copies
+// of encrypted snippets hold plaintext, so those are shredded instead.
+func (s *editSession) keepCopy() string {
+	if s.meta.Encoding == storage.EncodingEncrypted {
+		return "your edit was not kept, as the snippet is encrypted"
+	}
+	s.kept = true
+	return "your copy is at " + s.tmp
+}
+
+func (s *editSession) vars() runner.Vars {
+	return runner.Vars{Path: s.tmp, Key: s.key, Line: s.line}
+}
+
+func (s *editSession) render(header model.Metadata, content []byte) ([]byte, error) {
+	if !s.withHeader {
+		return content, nil
+	}
+	if !s.withContent {
+		content = nil
+	}
+	return formatFrontMatter(header, content)
+}
+
+func (s *editSession) write(buffer []byte) error {
+	if err := storage.Save(s.tmp, bytes.NewReader(buffer)); err != nil {
+		return err
+	}
+	s.unchanged = sha256.Sum256(buffer)
+	return nil
+}
+
+// settle reviews a copy the editor has closed, reopening it as often as
+// parse errors or merges need, and saves the result.
+func (e *Editor) settle(ctx context.Context, s *editSession) EditResult {
+	res := EditResult{Key: s.key, Metadata: s.meta}
+	for {
+		state, err := e.review(ctx, s)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		if state == editUnchanged {
+			return res
+		}
+		if state == editReady {
+			break
+		}
+		if err := e.Open(runner.WithVars(ctx, s.vars()), s.tmp); err != nil {
+			res.Err = err
+			return res
+		}
+	}
+
+	res.Metadata, res.Changed, res.Err = e.save(ctx, s)
+	return res
+}
+
+// review reads the copy back and decides what to do with it.
+func (e *Editor) review(ctx context.Context, s *editSession) (editState, error) {
+	edited, err := storage.Read(s.tmp)
 	if err != nil {
-		return model.Metadata{}, err
+		return editUnchanged, err
+	}
+
+	// Conflict markers from a merge are saved even if left untouched.
+	if sha256.Sum256(edited) == s.unchanged && (!s.merged || s.parseErr != nil) {
+		if s.parseErr != nil {
+			return editUnchanged, fmt.Errorf("front-matter: %w; %s", s.parseErr, s.keepCopy())
+		}
+		return editUnchanged, nil
 	}
 
-	meta.Type = detectType(data)
-	meta.Modified = e.Now().UTC()
+	s.content = edited
+	if s.withHeader {
+		if s.updated, s.content, s.parseErr = parseFrontMatter(s.updated, edited); s.parseErr != nil {
+			if err := s.write(withParseError(edited, s.parseErr)); err != nil {
+				return editUnchanged, err
+			}
+			return editReopen, nil
+		}
+	}
+	if !s.withContent {
+		s.content = s.original
+		return editReady, nil
+	}
 
-	if err := storage.UpdateMetadata(ctx, e.DB, meta); err != nil {
-		return model.Metadata{}, err
+	current, err := e.current(ctx, s)
+	if err != nil {
+		return editUnchanged, err
+	}
+	if sha256.Sum256(current) == s.base {
+		return editReady, nil
+	}
+
+	resolution := ResolveAbort
+	if e.Resolve != nil {
+		resolution, err = e.Resolve(ctx, Conflict{Key: s.key, Edited: s.content, OnDisk: current})
+		if err != nil {
+			return editUnchanged, err
+		}
 	}
 
-	return meta, nil
+	switch resolution {
+	case ResolveOverwrite:
+		return editReady, nil
+	case ResolveMerge:
+		buffer, err := s.render(s.updated, conflictMarkers(s.content, current))
+		if err != nil {
+			return editUnchanged, err
+		}
+		if err := s.write(buffer); err != nil {
+			return editUnchanged, err
+		}
+		s.base = sha256.Sum256(current)
+		s.merged = true
+		return editReopen, nil
+	default:
+		return editUnchanged, fmt.Errorf("%w: %q changed while editing; %s", ErrEditAborted, s.key, s.keepCopy())
+	}
+}
+
+// current reads the snippet as it is now. It may have been stored in
+// another encoding since the edit began, so that is looked up again.
+func (e *Editor) current(ctx context.Context, s *editSession) ([]byte, error) {
+	meta, err := storage.GetMetadata(ctx, e.DB, s.key)
+	if err != nil {
+		return nil, err
+	}
+	return storage.ReadDecrypted(s.path, meta.Encoding, e.Passphrase)
+}
+
+// save writes the reviewed copy back and updates the snippet's metadata.
+func (e *Editor) save(ctx context.Context, s *editSession) (model.Metadata, bool, error) {
+	updated := s.updated
+	contentChanged := sha256.Sum256(s.content) != s.base
+	if contentChanged {
+		encrypt := s.meta.Encoding == storage.EncodingEncrypted
+		if !encrypt && e.Secrets != nil && e.Secrets.Policy != SecretsOff {
+			if findings := e.Secrets.Scan(s.content); len(findings) > 0 {
+				switch e.Secrets.Policy {
+				case SecretsBlock:
+					return s.meta, false, fmt.Errorf("%w: %s; %s", ErrSecretFound, DescribeSecrets(findings), s.keepCopy())
+				case SecretsEncrypt:
+					encrypt = true
+				default:
+					updated.Tags = MergeTags(updated.Tags, []string{SecretTag}, nil)
+				}
+				if e.Warn != nil {
+					e.Warn(s.key, findings)
+				}
+			}
+		}
+
+		var pass []byte
+		if encrypt {
+			if e.Passphrase == nil {
+				return s.meta, false, errors.New("no passphrase to encrypt with")
+			}
+			var err error
+			if pass, err = e.Passphrase(); err != nil {
+				return s.meta, false, err
+			}
+		}
+		encoding, err := writeContent(s.path, s.content, e.CompressAbove, pass)
+		if err != nil {
+			return s.meta, false, err
+		}
+		updated.Encoding = encoding
+		// An explicit type or language in the front-matter wins over detection.
+		detected := Detect(s.key, s.content)
+		if updated.Type == s.meta.Type && updated.Language == s.meta.Language {
+			updated.Type, updated.Language = detected.Type, detected.Language
+		}
+		updated.MIME, updated.Size = detected.MIME, int64(len(s.content))
+		digest := sha256.Sum256(s.content)
+		updated.SHA256 = hex.EncodeToString(digest[:])
+		if encoding == storage.EncodingEncrypted {
+			if updated.SHA256, err = storage.HashFile(s.path, encoding); err != nil {
+				return s.meta, false, err
+			}
+		}
+	}
+
+	if !contentChanged && sameMetadata(updated, s.meta) {
+		return s.meta, false, nil
+	}
+	updated.Modified = e.Now().UTC()
+
+	if err := storage.UpdateMetadata(ctx, e.DB, updated); err != nil {
+		return s.meta, contentChanged, err
+	}
+
+	if err := e.Hooks.Run(ctx, hooks.NewPayload(hooks.PostEdit, s.path, updated)); err != nil {
+		return updated, true, err
+	}
+
+	return updated, true, nil
+}
+
+// sameMetadata reports whether the user-editable fields of a and b match.
+func sameMetadata(a, b model.Metadata) bool {
+	return a.Description == b.Description &&
+		a.Tags == b.Tags &&
+		a.Type == b.Type &&
+		a.Language == b.Language &&
+		maps.Equal(a.Fields, b.Fields)
+}
+
+// removeScratch shreds the copies of encrypted snippets, then removes
+// the edit dir unless a copy is kept there. Sessions may be nil.
+func removeScratch(dir string, keep bool, sessions ...*editSession) {
+	for _, s := range sessions {
+		if s != nil && s.tmp != "" && s.meta.Encoding == storage.EncodingEncrypted {
+			_ = storage.Shred(s.tmp)
+		}
+	}
+	if !keep {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// scratchFile writes data to a private temp directory under name, so
+// editors can pick a syntax from the extension. Callers remove the
+// file's directory when done.
+func scratchFile(name string, data []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "wow-edit-")
+	if err != nil {
+		return "", fmt.Errorf("create edit dir: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := storage.Save(path, bytes.NewReader(data)); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return path, nil
+}
+
+// conflictMarkers wraps both versions in git-style conflict markers.
+func conflictMarkers(edited, onDisk []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<<<<<<< edited\n")
+	buf.Write(withNewline(edited))
+	buf.WriteString("=======\n")
+	buf.Write(withNewline(onDisk))
+	buf.WriteString(">>>>>>> on disk\n")
+	return buf.Bytes()
+}
+
+func withNewline(data []byte) []byte {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return data
+	}
+	return append(data[:len(data):len(data)], '\n')
 }
@@ -2,12 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/llywelwyn/wow/internal/runner"
 	"github.com/llywelwyn/wow/internal/storage"
 )
 
@@ -61,7 +64,7 @@ func TestEditorEditUpdatesMetadataWhenFileChanges(t *testing.T) {
 		return os.WriteFile(path, []byte("https://example.com\n"), 0o600)
 	}
 
-	meta, err := editor.Edit(ctx, "go/foo")
+	meta, err := editor.Edit(ctx, "go/foo", EditOptions{})
 	if err != nil {
 		t.Fatalf("Edit error = %v", err)
 	}
@@ -89,6 +92,10 @@ func TestEditorEditUpdatesMetadataWhenFileChanges(t *testing.T) {
 	if !stored.Created.Equal(original.Created) {
 		t.Fatalf("stored Created changed = %v, want %v", stored.Created, original.Created)
 	}
+	// sha256 of "https://example.com\n".
+	if want := "65cdccbca9388a68023519f997367783be69ed42864398cac568e56f65ce0e75"; stored.SHA256 != want {
+		t.Fatalf("stored SHA256 = %q, want %q", stored.SHA256, want)
+	}
 }
 
 func TestEditorEditNoChangeLeavesMetadataUntouched(t *testing.T) {
@@ -110,7 +117,7 @@ func TestEditorEditNoChangeLeavesMetadataUntouched(t *testing.T) {
 		return nil
 	}
 
-	meta, err := editor.Edit(ctx, "go/foo")
+	meta, err := editor.Edit(ctx, "go/foo", EditOptions{})
 	if err != nil {
 		t.Fatalf("Edit error = %v", err)
 	}
@@ -122,3 +129,461 @@ func TestEditorEditNoChangeLeavesMetadataUntouched(t *testing.T) {
 		t.Fatalf("Type changed = %q, want %q", meta.Type, original.Type)
 	}
 }
+
+func TestEditorEditDetectsSameSizeChange(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+
+	if _, err := saver.Save(ctx, SaveRequest{
+		Key:    "notes/a",
+		Reader: strings.NewReader("aaaa\n"),
+	}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	editor.Open = func(ctx context.Context, path string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte("bbbb\n"), 0o600); err != nil {
+			return err
+		}
+		return os.Chtimes(path, info.ModTime(), info.ModTime())
+	}
+
+	meta, err := editor.Edit(ctx, "notes/a", EditOptions{})
+	if err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+	if !meta.Modified.Equal(editor.Now().UTC()) {
+		t.Fatalf("Modified = %v, want %v", meta.Modified, editor.Now().UTC())
+	}
+
+	data, err := os.ReadFile(filepath.Join(editor.BaseDir, "notes", "a"))
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if string(data) != "bbbb\n" {
+		t.Fatalf("content = %q, want edited", data)
+	}
+}
+
+// editConcurrently saves notes/a, then edits it while another writer
+// replaces the snippet, resolving the conflict with resolution.
+func editConcurrently(t *testing.T, resolution Resolution) (*Editor, []Conflict, error) {
+	t.Helper()
+
+	editor, saver, ctx := newEditEnv(t)
+	if _, err := saver.Save(ctx, SaveRequest{
+		Key:    "notes/a",
+		Reader: strings.NewReader("original\n"),
+	}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	snippet := filepath.Join(editor.BaseDir, "notes", "a")
+	opened := 0
+	editor.Open = func(ctx context.Context, path string) error {
+		opened++
+		if opened > 1 {
+			return os.WriteFile(path, []byte("merged\n"), 0o600)
+		}
+		if err := os.WriteFile(snippet, []byte("theirs\n"), 0o600); err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte("mine\n"), 0o600)
+	}
+
+	var conflicts []Conflict
+	editor.Resolve = func(ctx context.Context, c Conflict) (Resolution, error) {
+		conflicts = append(conflicts, c)
+		return resolution, nil
+	}
+
+	_, err := editor.Edit(ctx, "notes/a", EditOptions{})
+	return editor, conflicts, err
+}
+
+func TestEditorEditConflictAbortKeepsDisk(t *testing.T) {
+	editor, conflicts, err := editConcurrently(t, ResolveAbort)
+	if !errors.Is(err, ErrEditAborted) {
+		t.Fatalf("Edit error = %v, want ErrEditAborted", err)
+	}
+	if len(conflicts) != 1 || string(conflicts[0].Edited) != "mine\n" || string(conflicts[0].OnDisk) != "theirs\n" {
+		t.Fatalf("conflicts = %+v", conflicts)
+	}
+	if got := readFile(t, editor, "notes/a"); got != "theirs\n" {
+		t.Fatalf("content = %q, want theirs", got)
+	}
+}
+
+func TestEditorEditConflictOverwrite(t *testing.T) {
+	editor, _, err := editConcurrently(t, ResolveOverwrite)
+	if err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+	if got := readFile(t, editor, "notes/a"); got != "mine\n" {
+		t.Fatalf("content = %q, want mine", got)
+	}
+}
+
+func TestEditorEditConflictMergeReopens(t *testing.T) {
+	editor, conflicts, err := editConcurrently(t, ResolveMerge)
+	if err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("conflicts = %d, want 1", len(conflicts))
+	}
+	if got := readFile(t, editor, "notes/a"); got != "merged\n" {
+		t.Fatalf("content = %q, want merged", got)
+	}
+}
+
+func TestEditorEditMetaUpdatesFrontMatterFields(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+
+	if _, err := saver.Save(ctx, SaveRequest{
+		Key:    "ops/deploy",
+		Tags:   []string{"ops"},
+		Reader: strings.NewReader("kubectl apply\n"),
+	}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	editor.Open = func(ctx context.Context, path string) error {
+		return os.WriteFile(path, []byte("---\ndescription: deploy notes\ntags: [ops, k8s]\ntype: text\nowner: sam\n---\n"), 0o600)
+	}
+
+	if _, err := editor.Edit(ctx, "ops/deploy", EditOptions{Mode: EditMeta}); err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+
+	stored, err := storage.GetMetadata(ctx, editor.DB, "ops/deploy")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if stored.Description != "deploy notes" || stored.Tags != "ops,k8s" || stored.Fields["owner"] != "sam" {
+		t.Fatalf("stored = %+v", stored)
+	}
+	if got := readFile(t, editor, "ops/deploy"); got != "kubectl apply\n" {
+		t.Fatalf("content = %q, want untouched", got)
+	}
+}
+
+func TestEditorEditMetaReopensOnParseError(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+
+	if _, err := saver.Save(ctx, SaveRequest{
+		Key:    "ops/deploy",
+		Reader: strings.NewReader("kubectl apply\n"),
+	}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	var seen []string
+	editor.Open = func(ctx context.Context, path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		seen = append(seen, string(data))
+		if len(seen) == 1 {
+			return os.WriteFile(path, []byte("---\ntags: [ops\n---\n"), 0o600)
+		}
+		return os.WriteFile(path, []byte("---\ntags: [ops]\ntype: text\n---\n"), 0o600)
+	}
+
+	if _, err := editor.Edit(ctx, "ops/deploy", EditOptions{Mode: EditMeta}); err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+	if len(seen) != 2 || !strings.Contains(seen[1], "# error: ") || !strings.Contains(seen[1], "tags: [ops\n") {
+		t.Fatalf("second buffer = %q, want the edit with an error comment", seen)
+	}
+
+	stored, err := storage.GetMetadata(ctx, editor.DB, "ops/deploy")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if stored.Tags != "ops" {
+		t.Fatalf("Tags = %q, want ops", stored.Tags)
+	}
+}
+
+func TestEditorEditAllUpdatesHeaderAndContent(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+
+	if _, err := saver.Save(ctx, SaveRequest{
+		Key:    "ops/deploy",
+		Reader: strings.NewReader("kubectl apply\n"),
+	}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	editor.Open = func(ctx context.Context, path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(string(data), "---\nkubectl apply\n") {
+			t.Fatalf("buffer = %q, want content after header", data)
+		}
+		return os.WriteFile(path, []byte("---\ndescription: rollout\ntype: text\n---\nkubectl rollout status\n"), 0o600)
+	}
+
+	if _, err := editor.Edit(ctx, "ops/deploy", EditOptions{Mode: EditAll}); err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+
+	stored, err := storage.GetMetadata(ctx, editor.DB, "ops/deploy")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if stored.Description != "rollout" {
+		t.Fatalf("Description = %q, want rollout", stored.Description)
+	}
+	if got := readFile(t, editor, "ops/deploy"); got != "kubectl rollout status\n" {
+		t.Fatalf("content = %q", got)
+	}
+}
+
+func readFile(t *testing.T, editor *Editor, k string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(editor.BaseDir, filepath.FromSlash(k)))
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	return string(data)
+}
+
+func TestEditorEditPassesLineToEditor(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+
+	if _, err := saver.Save(ctx, SaveRequest{
+		Key:    "ops/deploy",
+		Reader: strings.NewReader("one\ntwo\nrollback\n"),
+	}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	var lines []int
+	editor.Open = func(ctx context.Context, path string) error {
+		out := filepath.Join(t.TempDir(), "line")
+		fn := runner.Run(`sh -c 'printf %s "$1" > "`+out+`"' sh +{line}`, nil)
+		if err := fn(ctx, path); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			return err
+		}
+		var n int
+		if _, err := fmt.Sscanf(string(data), "+%d", &n); err != nil {
+			return err
+		}
+		lines = append(lines, n)
+		return nil
+	}
+
+	if _, err := editor.Edit(ctx, "ops/deploy", EditOptions{At: Position{Search: "rollback"}}); err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+	if _, err := editor.Edit(ctx, "ops/deploy", EditOptions{Mode: EditAll, At: Position{Line: 1}}); err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+	if _, err := editor.Edit(ctx, "ops/deploy", EditOptions{Mode: EditMeta, At: Position{Line: 1}}); err == nil {
+		t.Fatalf("expected error for a line with --meta")
+	}
+
+	// Line 1 of the content sits below the front-matter with --all.
+	if len(lines) != 2 || lines[0] != 3 || lines[1] <= 1 {
+		t.Fatalf("lines = %v", lines)
+	}
+}
+
+func TestEditorEditManyOpensOnceAndSavesEach(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+
+	for _, k := range []string{"k8s/deploy", "k8s/service", "ops/notes"} {
+		if _, err := saver.Save(ctx, SaveRequest{Key: k, Reader: strings.NewReader(k + "\n")}); err != nil {
+			t.Fatalf("Save error = %v", err)
+		}
+	}
+
+	var opened [][]string
+	editor.Open = func(ctx context.Context, path string) error {
+		out := filepath.Join(t.TempDir(), "args")
+		fn := runner.Run(`sh -c 'printf "%s\n" "$@" > "`+out+`"' sh`, nil)
+		if err := fn(ctx, path); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			return err
+		}
+		files := strings.Fields(string(data))
+		opened = append(opened, files)
+		// Change only the deploy snippet.
+		for _, file := range files {
+			if strings.HasSuffix(file, filepath.Join("k8s", "deploy")) {
+				return os.WriteFile(file, []byte("edited\n"), 0o600)
+			}
+		}
+		return nil
+	}
+
+	results, err := editor.EditMany(ctx, []string{"k8s/*", "k8s/deploy"}, EditOptions{})
+	if err != nil {
+		t.Fatalf("EditMany error = %v", err)
+	}
+
+	if len(opened) != 1 || len(opened[0]) != 2 {
+		t.Fatalf("editor sessions = %v, want one with two files", opened)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v, want 2", results)
+	}
+	if results[0].Key != "k8s/deploy" || !results[0].Changed || results[0].Err != nil {
+		t.Fatalf("results[0] = %+v", results[0])
+	}
+	if results[1].Key != "k8s/service" || results[1].Changed || results[1].Err != nil {
+		t.Fatalf("results[1] = %+v", results[1])
+	}
+	if got := readFile(t, editor, "k8s/deploy"); got != "edited\n" {
+		t.Fatalf("content = %q", got)
+	}
+}
+
+func TestEditorEditManyRejectsUnmatchedPattern(t *testing.T) {
+	editor, _, ctx := newEditEnv(t)
+	editor.Open = func(context.Context, string) error {
+		t.Fatalf("editor should not open")
+		return nil
+	}
+	if _, err := editor.EditMany(ctx, []string{"nothing/*"}, EditOptions{}); err == nil {
+		t.Fatalf("expected error for a pattern matching nothing")
+	}
+}
+
+func TestEditorEditCompressedSnippet(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+	saver.CompressAbove = 1
+	editor.CompressAbove = 1
+
+	content := strings.Repeat("2024-01-01 worker started\n", 100)
+	if _, err := saver.Save(ctx, SaveRequest{Key: "logs/worker", Reader: strings.NewReader(content)}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	var seen string
+	editor.Open = func(ctx context.Context, path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		seen = string(data)
+		return os.WriteFile(path, append(data, "2024-01-01 worker stopped\n"...), 0o600)
+	}
+
+	meta, err := editor.Edit(ctx, "logs/worker", EditOptions{})
+	if err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+	if seen != content {
+		t.Fatalf("editor got %d bytes, want the %d decompressed", len(seen), len(content))
+	}
+	if meta.Encoding != storage.EncodingGzip {
+		t.Fatalf("Encoding = %q, want gzip", meta.Encoding)
+	}
+
+	path := filepath.Join(editor.BaseDir, "logs", "worker")
+	data, err := storage.ReadEncoded(path, meta.Encoding)
+	if err != nil {
+		t.Fatalf("ReadEncoded error = %v", err)
+	}
+	if want := content + "2024-01-01 worker stopped\n"; string(data) != want {
+		t.Fatalf("stored content = %q, want the edit", data)
+	}
+}
+
+func TestEditorEditEncryptedSnippet(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+	passphrase := func() ([]byte, error) { return []byte("pass"), nil }
+	saver.Passphrase = passphrase
+	editor.Passphrase = passphrase
+
+	if _, err := saver.Save(ctx, SaveRequest{Key: "db/prod", Reader: strings.NewReader("hunter2\n"), Encrypt: true}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	var copyPath string
+	editor.Open = func(ctx context.Context, path string) error {
+		copyPath = path
+		return os.WriteFile(path, []byte("hunter3\n"), 0o600)
+	}
+	meta, err := editor.Edit(ctx, "db/prod", EditOptions{})
+	if err != nil {
+		t.Fatalf("Edit error = %v", err)
+	}
+	if meta.Encoding != storage.EncodingEncrypted {
+		t.Fatalf("Encoding = %q, want it encrypted again", meta.Encoding)
+	}
+	if _, err := os.Stat(copyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the plaintext copy is still at %s", copyPath)
+	}
+
+	path := filepath.Join(editor.BaseDir, "db", "prod")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if strings.Contains(string(raw), "hunter") {
+		t.Fatalf("snippet file holds plaintext: %q", raw)
+	}
+	data, err := storage.ReadDecrypted(path, meta.Encoding, passphrase)
+	if err != nil {
+		t.Fatalf("ReadDecrypted error = %v", err)
+	}
+	if string(data) != "hunter3\n" {
+		t.Fatalf("stored content = %q, want the edit", data)
+	}
+	if sum, _ := storage.HashFile(path, meta.Encoding); sum != meta.SHA256 {
+		t.Fatalf("SHA256 = %s, want the hash of the sealed file %s", meta.SHA256, sum)
+	}
+}
+
+func TestEditorEditEncryptedConflictShredsCopy(t *testing.T) {
+	editor, saver, ctx := newEditEnv(t)
+	passphrase := func() ([]byte, error) { return []byte("pass"), nil }
+	saver.Passphrase = passphrase
+	editor.Passphrase = passphrase
+
+	if _, err := saver.Save(ctx, SaveRequest{Key: "db/prod", Reader: strings.NewReader("hunter2\n"), Encrypt: true}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	snippet := filepath.Join(editor.BaseDir, "db", "prod")
+	var copyPath string
+	editor.Open = func(ctx context.Context, path string) error {
+		copyPath = path
+		if _, err := writeContent(snippet, []byte("theirs\n"), 0, []byte("pass")); err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte("hunter3\n"), 0o600)
+	}
+	editor.Resolve = func(context.Context, Conflict) (Resolution, error) { return ResolveAbort, nil }
+
+	_, err := editor.Edit(ctx, "db/prod", EditOptions{})
+	if !errors.Is(err, ErrEditAborted) {
+		t.Fatalf("Edit error = %v, want ErrEditAborted", err)
+	}
+	if strings.Contains(err.Error(), copyPath) {
+		t.Fatalf("error %q points at a plaintext copy", err)
+	}
+	if _, err := os.Stat(copyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the plaintext copy is still at %s", copyPath)
+	}
+	if _, err := os.Stat(filepath.Dir(filepath.Dir(copyPath))); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the edit dir was left behind, stat err = %v", err)
+	}
+}
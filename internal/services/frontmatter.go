@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/llywelwyn/wow/internal/model"
+)
+
+const frontMatterFence = "---"
+
+// errorComment prefixes the parse errors written back into a buffer.
+const errorComment = "# error: "
+
+// frontMatter is the editable header of a snippet.
+type frontMatter struct {
+	Description string            `yaml:"description"`
+	Tags        []string          `yaml:"tags,flow"`
+	Type        string            `yaml:"type"`
+	Language    string            `yaml:"language,omitempty"`
+	Fields      map[string]string `yaml:",inline"`
+}
+
+// reservedFields cannot be set as custom fields.
+var reservedFields = map[string]bool{"key": true, "created": true, "modified": true, "vault": true, "mime": true, "size": true, "encoding": true, "sha256": true}
+
+// formatFrontMatter renders meta as a YAML block, followed by content.
+func formatFrontMatter(meta model.Metadata, content []byte) ([]byte, error) {
+	header, err := yaml.Marshal(frontMatter{
+		Description: meta.Description,
+		Tags:        parseTags(meta.Tags),
+		Type:        meta.Type,
+		Language:    meta.Language,
+		Fields:      meta.Fields,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("format front-matter: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterFence + "\n")
+	fmt.Fprintf(&buf, "# %s: created %s, modified %s\n",
+		meta.Key, meta.Created.Local().Format("2006-01-02 15:04"), meta.Modified.Local().Format("2006-01-02 15:04"))
+	buf.Write(header)
+	buf.WriteString(frontMatterFence + "\n")
+	buf.Write(content)
+	return buf.Bytes(), nil
+}
+
+// parseFrontMatter applies the header in data to meta and returns the
+// content that follows it.
+func parseFrontMatter(meta model.Metadata, data []byte) (model.Metadata, []byte, error) {
+	text := strings.Join(withoutErrorComments(data), "")
+	rest, ok := strings.CutPrefix(text, frontMatterFence+"\n")
+	if !ok {
+		return meta, nil, errors.New(`front-matter must start with a "---" line`)
+	}
+
+	var header, content string
+	if h, c, found := strings.Cut(rest, "\n"+frontMatterFence+"\n"); found {
+		header, content = h, c
+	} else if h, found := strings.CutSuffix(rest, "\n"+frontMatterFence); found {
+		header = h
+	} else {
+		return meta, nil, errors.New(`front-matter must end with a "---" line`)
+	}
+
+	var fm frontMatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return meta, nil, err
+	}
+
+	fm.Type = strings.TrimSpace(fm.Type)
+	if fm.Type == "" {
+		return meta, nil, errors.New("type is required")
+	}
+	for name := range fm.Fields {
+		if reservedFields[name] {
+			return meta, nil, fmt.Errorf("%q cannot be changed here", name)
+		}
+	}
+
+	meta.Description = strings.TrimSpace(fm.Description)
+	meta.Tags = MergeTags("", fm.Tags, nil)
+	meta.Type = fm.Type
+	meta.Language = strings.TrimSpace(fm.Language)
+	meta.Fields = nil
+	if len(fm.Fields) > 0 {
+		meta.Fields = fm.Fields
+	}
+	return meta, []byte(content), nil
+}
+
+// withParseError writes err as comments at the top of the front-matter,
+// replacing any left from an earlier attempt.
+func withParseError(data []byte, err error) []byte {
+	kept := withoutErrorComments(data)
+
+	var comments []string
+	for _, line := range strings.Split(err.Error(), "\n") {
+		comments = append(comments, errorComment+line+"\n")
+	}
+
+	if len(kept) > 0 && strings.TrimRight(kept[0], "\n") == frontMatterFence {
+		return []byte(kept[0] + strings.Join(comments, "") + strings.Join(kept[1:], ""))
+	}
+	return []byte(strings.Join(comments, "") + strings.Join(kept, ""))
+}
+
+func withoutErrorComments(data []byte) []string {
+	var kept []string
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if !strings.HasPrefix(line, errorComment) {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
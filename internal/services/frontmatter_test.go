@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/model"
+)
+
+func TestFrontMatterRoundTrip(t *testing.T) {
+	meta := model.Metadata{
+		Key:         "ops/deploy",
+		Type:        "text",
+		Created:     time.Unix(1_700_000_000, 0),
+		Modified:    time.Unix(1_700_000_000, 0),
+		Description: "deploy notes",
+		Tags:        "ops,k8s",
+		Fields:      map[string]string{"owner": "sam"},
+	}
+
+	data, err := formatFrontMatter(meta, []byte("kubectl apply\n"))
+	if err != nil {
+		t.Fatalf("formatFrontMatter error = %v", err)
+	}
+	if !strings.Contains(string(data), "tags: [ops, k8s]\n") {
+		t.Fatalf("front-matter = %q, want flow tags", data)
+	}
+
+	got, content, err := parseFrontMatter(meta, data)
+	if err != nil {
+		t.Fatalf("parseFrontMatter error = %v", err)
+	}
+	if string(content) != "kubectl apply\n" {
+		t.Fatalf("content = %q", content)
+	}
+	if got.Description != meta.Description || got.Tags != meta.Tags || got.Type != meta.Type || got.Fields["owner"] != "sam" {
+		t.Fatalf("parsed = %+v, want %+v", got, meta)
+	}
+}
+
+func TestParseFrontMatterRejects(t *testing.T) {
+	cases := map[string]string{
+		"no fence":       "description: x\n",
+		"unclosed":       "---\ndescription: x\n",
+		"bad yaml":       "---\ntags: [a\n---\n",
+		"missing type":   "---\ndescription: x\n---\n",
+		"reserved field": "---\ntype: text\nkey: other\n---\n",
+		"nested field":   "---\ntype: text\nowner: [a, b]\n---\n",
+	}
+	for name, data := range cases {
+		if _, _, err := parseFrontMatter(model.Metadata{}, []byte(data)); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestWithParseErrorReplacesOldComments(t *testing.T) {
+	data := withParseError([]byte("---\ntags: [a\n---\n"), errors.New("first"))
+	data = withParseError(data, errors.New("second"))
+
+	want := "---\n# error: second\ntags: [a\n---\n"
+	if string(data) != want {
+		t.Fatalf("withParseError = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrNoMatch is returned when a search finds no line.
+var ErrNoMatch = errors.New("no line matches")
+
+// Position picks a line to open a snippet at.
+// Line wins when both are set; the zero value means the top.
+type Position struct {
+	Line   int
+	Search string // regular expression; the first matching line is used.
+}
+
+// lineIn resolves pos against data and returns a 1-based line, or zero.
+func (pos Position) lineIn(data []byte) (int, error) {
+	if pos.Line > 0 || pos.Search == "" {
+		return pos.Line, nil
+	}
+
+	re, err := regexp.Compile(pos.Search)
+	if err != nil {
+		return 0, fmt.Errorf("search: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		if re.Match(scanner.Bytes()) {
+			return n, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("search: %w", err)
+	}
+	return 0, fmt.Errorf("%w %q", ErrNoMatch, pos.Search)
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPositionLineIn(t *testing.T) {
+	data := []byte("# deploy\nkubectl apply\n# rollback\nkubectl rollout undo\n")
+
+	tests := []struct {
+		at   Position
+		want int
+	}{
+		{Position{}, 0},
+		{Position{Line: 7}, 7},
+		{Position{Search: "^# rollback"}, 3},
+		{Position{Search: "kubectl"}, 2},
+	}
+	for _, tc := range tests {
+		got, err := tc.at.lineIn(data)
+		if err != nil {
+			t.Fatalf("lineIn(%+v) error = %v", tc.at, err)
+		}
+		if got != tc.want {
+			t.Fatalf("lineIn(%+v) = %d, want %d", tc.at, got, tc.want)
+		}
+	}
+
+	if _, err := (Position{Search: "helm"}).lineIn(data); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("lineIn error = %v, want ErrNoMatch", err)
+	}
+	if _, err := (Position{Search: "("}).lineIn(data); err == nil {
+		t.Fatalf("expected error for bad regexp")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/llywelwyn/wow/internal/key"
@@ -13,8 +14,9 @@ import (
 
 // Metadata manages snippet metadata updates.
 type Metadata struct {
-	DB  *sql.DB
-	Now func() time.Time
+	DB    *sql.DB
+	Now   func() time.Time
+	Force bool // changes protected snippets too.
 }
 
 // TagUpdateResult reports the outcome of updating snippet tags.
@@ -39,6 +41,9 @@ func (m *Metadata) UpdateTags(ctx context.Context, rawKey string, add, remove []
 	if err != nil {
 		return TagUpdateResult{}, err
 	}
+	if err := refuseProtected(meta, m.Force); err != nil {
+		return TagUpdateResult{}, err
+	}
 
 	before := parseTags(meta.Tags)
 	updated := MergeTags(meta.Tags, add, remove)
@@ -62,6 +67,50 @@ func (m *Metadata) UpdateTags(ctx context.Context, rawKey string, add, remove []
 	}, nil
 }
 
+// SetType overrides the detected type of a snippet. An empty language
+// keeps the current one.
+func (m *Metadata) SetType(ctx context.Context, rawKey, typ, language string) (model.Metadata, error) {
+	if m.DB == nil || m.Now == nil {
+		return model.Metadata{}, errors.New("metadata service misconfigured")
+	}
+
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	language = strings.ToLower(strings.TrimSpace(language))
+	if typ == "" && language == "" {
+		return model.Metadata{}, errors.New("type or language required")
+	}
+
+	normalized, err := key.Normalize(rawKey)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+
+	meta, err := storage.GetMetadata(ctx, m.DB, normalized)
+	if err != nil {
+		return model.Metadata{}, err
+	}
+	if err := refuseProtected(meta, m.Force); err != nil {
+		return model.Metadata{}, err
+	}
+
+	updated := meta
+	if typ != "" {
+		updated.Type = typ
+	}
+	if language != "" {
+		updated.Language = language
+	}
+	if updated.Type == meta.Type && updated.Language == meta.Language {
+		return meta, nil
+	}
+
+	updated.Modified = m.Now().UTC()
+	if err := storage.UpdateMetadata(ctx, m.DB, updated); err != nil {
+		return model.Metadata{}, err
+	}
+	return updated, nil
+}
+
 func diff(a, b []string) []string {
 	if len(a) == 0 {
 		return nil
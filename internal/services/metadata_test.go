@@ -48,3 +48,43 @@ func TestMetadataUpdateTags(t *testing.T) {
 		t.Fatalf("Removed = %v, want [foo]", result.Removed)
 	}
 }
+
+func TestMetadataSetType(t *testing.T) {
+	base := t.TempDir()
+	db, err := storage.InitMetaDB(filepath.Join(base, "meta.db"))
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	saver := &Saver{BaseDir: base, DB: db, Now: func() time.Time { return time.Unix(1_700_000_000, 0) }}
+	ctx := context.Background()
+	if _, err := saver.Save(ctx, SaveRequest{Key: "ops/deploy", Reader: strings.NewReader("kubectl apply -f .\n")}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	metaSvc := &Metadata{DB: db, Now: func() time.Time { return time.Unix(1_700_000_100, 0) }}
+	meta, err := metaSvc.SetType(ctx, "ops/deploy", "Script", "bash")
+	if err != nil {
+		t.Fatalf("SetType error = %v", err)
+	}
+	if meta.Type != "script" || meta.Language != "bash" || !meta.Modified.Equal(time.Unix(1_700_000_100, 0)) {
+		t.Fatalf("meta = %+v", meta)
+	}
+
+	// An empty language keeps the one set before.
+	if _, err := metaSvc.SetType(ctx, "ops/deploy", "code", ""); err != nil {
+		t.Fatalf("SetType error = %v", err)
+	}
+	stored, err := storage.GetMetadata(ctx, db, "ops/deploy")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if stored.Type != "code" || stored.Language != "bash" {
+		t.Fatalf("stored = %+v", stored)
+	}
+
+	if _, err := metaSvc.SetType(ctx, "ops/deploy", " ", ""); err == nil {
+		t.Fatalf("expected error without a type or language")
+	}
+}
@@ -1,76 +1,372 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/llywelwyn/wow/internal/hooks"
 	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/runner"
 	"github.com/llywelwyn/wow/internal/storage"
+	"github.com/llywelwyn/wow/internal/vault"
 )
 
 // OpenOptions controls how a snippet should be opened.
 type OpenOptions struct {
 	UsePager bool
+	With     string   // name of a rule to use, skipping rule matching.
+	Raw      bool     // skip ViewFunc, so Markdown goes to OpenFunc.
+	At       Position // line to open at, for programs that support one.
+}
+
+// systemCopyPrefix names the directories holding copies handed to the
+// system opener.
+const systemCopyPrefix = "wow-open-"
+
+// systemCopyGrace is how long a copy handed to the system opener is
+// kept for its program to read, before a later open removes it.
+const systemCopyGrace = time.Hour
+
+// ErrUnknownRule is returned when OpenOptions.With names no rule.
+var ErrUnknownRule = errors.New("unknown open rule")
+
+// OpenRule routes matching snippets to a specific program.
+// Empty conditions match anything; every given condition must match.
+type OpenRule struct {
+	Name    string
+	Keys    []string // key globs; a glob without "/" matches the last segment.
+	Type    string
+	Lang    string
+	MIME    []string // media type globs, e.g. "image/*".
+	Command string   // the command Open runs, for display.
+	Open    func(context.Context, string) error
+}
+
+// OpenPlan describes how a snippet will be opened.
+type OpenPlan struct {
+	Key     string
+	Path    string
+	Target  string // what the program is given: the file, or a url snippet's URL.
+	Line    int    // line to open at; zero for the top.
+	Rule    string // name of the chosen rule; empty for the pager or default opener.
+	Command string // the chosen rule's command.
+	View    bool   // rendered by ViewFunc rather than an external program.
+	System  bool   // handed to SystemFunc, which picks a program by media type.
+	Reason  string // why the program was chosen.
+
+	meta model.Metadata
+	vars runner.Vars
+	run  func(context.Context, string) error
 }
 
 // Opener launches external programs to view snippets.
 type Opener struct {
-	BaseDir   string
-	DB        *sql.DB
-	OpenFunc  func(context.Context, string) error
-	PagerFunc func(context.Context, string) error
+	BaseDir    string
+	DB         *sql.DB
+	OpenFunc   func(context.Context, string) error
+	PagerFunc  func(context.Context, string) error
+	ViewFunc   func(context.Context, string) error // renders Markdown no rule matches; nil uses OpenFunc.
+	SystemFunc func(context.Context, string) error // opens binary no rule matches by media type; nil uses OpenFunc.
+	Rules      []OpenRule                          // tried in order before OpenFunc; the first match wins.
+	Vaults     vault.Stack                         // search path; defaults to a single vault at BaseDir.
+	Hooks      *hooks.Runner
+	Passphrase storage.Passphrase // supplies the passphrase for encrypted snippets.
+	TempDir    string             // where copies for SystemFunc are kept; empty uses os.TempDir().
 }
 
 // Open opens the snippet referred to by key with the configured program.
 func (o *Opener) Open(ctx context.Context, rawKey string, opts OpenOptions) error {
+	plan, err := o.Plan(ctx, rawKey, opts)
+	if err != nil {
+		return err
+	}
+
+	// Programs are given a decoded copy of compressed or encrypted
+	// snippets. System openers may return before their program reads
+	// the file, so their copy is kept for a while and removed by a
+	// later open; as plaintext must not be left behind, encrypted
+	// snippets need a rule.
+	encrypted := plan.meta.Encoding == storage.EncodingEncrypted
+	if plan.meta.Encoding != storage.EncodingNone && plan.Target == plan.Path {
+		if encrypted && plan.System {
+			return fmt.Errorf("%q is encrypted and cannot be handed to the system opener; open it --with a rule", plan.Key)
+		}
+		data, err := storage.ReadDecrypted(plan.Path, plan.meta.Encoding, o.Passphrase)
+		if err != nil {
+			return err
+		}
+		var copyPath string
+		if plan.System {
+			o.sweepSystemCopies(time.Now().Add(-systemCopyGrace))
+			copyPath, err = o.systemCopy(path.Base(plan.Key), data)
+		} else {
+			copyPath, err = scratchFile(path.Base(plan.Key), data)
+		}
+		clear(data)
+		if err != nil {
+			return err
+		}
+		if !plan.System {
+			defer func() {
+				if encrypted {
+					_ = storage.Shred(copyPath)
+				}
+				_ = os.RemoveAll(filepath.Dir(copyPath))
+			}()
+		}
+		plan.Target, plan.vars.Path = copyPath, copyPath
+	}
+
+	if err := plan.run(runner.WithVars(ctx, plan.vars), plan.Target); err != nil {
+		return err
+	}
+
+	return o.Hooks.Run(ctx, hooks.NewPayload(hooks.PostOpen, plan.Path, plan.meta))
+}
+
+// systemCopy writes data to a fresh directory under TempDir for the
+// system opener.
+func (o *Opener) systemCopy(name string, data []byte) (string, error) {
+	dir, err := os.MkdirTemp(o.TempDir, systemCopyPrefix)
+	if err != nil {
+		return "", fmt.Errorf("create open dir: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := storage.Save(path, bytes.NewReader(data)); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return path, nil
+}
+
+// sweepSystemCopies removes the copies systemCopy made before cutoff.
+// Failures are ignored; the next open tries again.
+func (o *Opener) sweepSystemCopies(cutoff time.Time) {
+	dir := o.TempDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), systemCopyPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		_ = os.RemoveAll(filepath.Join(dir, entry.Name()))
+	}
+}
+
+// Plan works out how Open would open key, without running anything.
+func (o *Opener) Plan(ctx context.Context, rawKey string, opts OpenOptions) (OpenPlan, error) {
 	if o.DB == nil {
-		return errors.New("opener misconfigured: db required")
+		return OpenPlan{}, errors.New("opener misconfigured: db required")
 	}
 	if o.OpenFunc == nil {
-		return errors.New("opener misconfigured: open func required")
+		return OpenPlan{}, errors.New("opener misconfigured: open func required")
 	}
 	if o.PagerFunc == nil {
-		return errors.New("opener misconfigured: pager func required")
+		return OpenPlan{}, errors.New("opener misconfigured: pager func required")
 	}
 
 	normalized, err := key.Normalize(rawKey)
 	if err != nil {
-		return err
+		return OpenPlan{}, err
 	}
 
-	meta, err := storage.GetMetadata(ctx, o.DB, normalized)
+	found, meta, err := o.vaults().Lookup(ctx, normalized)
 	if err != nil {
-		return err
+		return OpenPlan{}, err
 	}
 
-	path, err := key.ResolvePath(o.BaseDir, normalized)
+	path, err := key.ResolvePath(found.BaseDir, normalized)
 	if err != nil {
-		return err
+		return OpenPlan{}, err
+	}
+
+	plan := OpenPlan{
+		Key:    normalized,
+		Path:   path,
+		Target: path,
+		meta:   meta,
+		vars:   runner.Vars{Path: path, Key: normalized},
+	}
+
+	if opts.At != (Position{}) {
+		data, err := storage.ReadDecrypted(path, meta.Encoding, o.Passphrase)
+		if err != nil {
+			return OpenPlan{}, err
+		}
+		if plan.vars.Line, err = opts.At.lineIn(data); err != nil {
+			return OpenPlan{}, err
+		}
+		plan.Line = plan.vars.Line
 	}
 
 	if opts.UsePager {
-		return o.PagerFunc(ctx, path)
+		if opts.With != "" {
+			return OpenPlan{}, errors.New("--pager and --with are mutually exclusive")
+		}
+		if meta.Type == "binary" {
+			return OpenPlan{}, fmt.Errorf("%q is binary (%s) and cannot be paged", normalized, meta.MIME)
+		}
+		plan.Reason = "pager requested"
+		plan.run = o.PagerFunc
+		return plan, nil
 	}
 
 	if meta.Type == "url" {
-		data, err := storage.Read(path)
+		data, err := storage.ReadDecrypted(path, meta.Encoding, o.Passphrase)
 		if err != nil {
-			return err
+			return OpenPlan{}, err
 		}
 		target := firstNonEmptyLine(data)
 		if target == "" {
 			target = string(data)
 		}
-		target = strings.TrimSpace(target)
-		if target == "" {
-			target = path
+		if target = strings.TrimSpace(target); target != "" {
+			plan.Target = target
+			plan.vars.URL = target
+		}
+	}
+
+	if opts.With != "" {
+		rule, ok := o.rule(opts.With)
+		if !ok {
+			return OpenPlan{}, fmt.Errorf("%w: %q", ErrUnknownRule, opts.With)
+		}
+		plan.Rule = rule.Name
+		plan.Command = rule.Command
+		plan.Reason = "chosen with --with"
+		plan.run = rule.Open
+		return plan, nil
+	}
+
+	lang := meta.Language
+	if lang == "" {
+		lang = detectLanguage(normalized)
+	}
+	for _, rule := range o.Rules {
+		if reason, ok := rule.match(meta, lang); ok {
+			plan.Rule = rule.Name
+			plan.Command = rule.Command
+			plan.Reason = reason
+			plan.run = rule.Open
+			return plan, nil
 		}
-		return o.OpenFunc(ctx, target)
 	}
 
-	return o.OpenFunc(ctx, path)
+	if lang == "markdown" && o.ViewFunc != nil && !opts.Raw {
+		plan.Reason = "no rule matched; markdown is rendered in the terminal"
+		plan.View = true
+		plan.run = o.ViewFunc
+		return plan, nil
+	}
+
+	if meta.Type == "binary" && o.SystemFunc != nil {
+		plan.Reason = fmt.Sprintf("no rule matched; binary is opened by media type %q", meta.MIME)
+		plan.System = true
+		plan.run = o.SystemFunc
+		return plan, nil
+	}
+
+	plan.Reason = "no rule matched"
+	plan.run = o.OpenFunc
+	return plan, nil
+}
+
+func (o *Opener) rule(name string) (OpenRule, bool) {
+	for _, rule := range o.Rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return OpenRule{}, false
+}
+
+// match reports whether the rule applies to meta, and explains why.
+func (r OpenRule) match(meta model.Metadata, lang string) (string, bool) {
+	var reasons []string
+	if len(r.Keys) > 0 {
+		pattern, ok := matchKey(r.Keys, meta.Key)
+		if !ok {
+			return "", false
+		}
+		reasons = append(reasons, fmt.Sprintf("key matches %q", pattern))
+	}
+	if r.Type != "" {
+		if r.Type != meta.Type {
+			return "", false
+		}
+		reasons = append(reasons, fmt.Sprintf("type is %q", r.Type))
+	}
+	if r.Lang != "" {
+		if r.Lang != lang {
+			return "", false
+		}
+		reasons = append(reasons, fmt.Sprintf("lang is %q", r.Lang))
+	}
+	if len(r.MIME) > 0 {
+		pattern, ok := matchMIME(r.MIME, meta.MIME)
+		if !ok {
+			return "", false
+		}
+		reasons = append(reasons, fmt.Sprintf("mime matches %q", pattern))
+	}
+	if len(reasons) == 0 {
+		return "matches every snippet", true
+	}
+	return strings.Join(reasons, ", "), true
+}
+
+// matchKey returns the first glob matching k. Globs without a "/"
+// are matched against the key's last segment, so "*.md" matches
+// "docs/readme.md".
+func matchKey(patterns []string, k string) (string, bool) {
+	for _, pattern := range patterns {
+		subject := k
+		if !strings.Contains(pattern, "/") {
+			subject = path.Base(k)
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// matchMIME returns the first glob matching the media type mt.
+func matchMIME(patterns []string, mt string) (string, bool) {
+	if mt == "" {
+		return "", false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, mt); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func (o *Opener) vaults() vault.Stack {
+	if len(o.Vaults) > 0 {
+		return o.Vaults
+	}
+	return vault.Stack{{Name: "user", BaseDir: o.BaseDir, DB: o.DB}}
 }
 
 func firstNonEmptyLine(data []byte) string {
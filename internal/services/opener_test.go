@@ -3,11 +3,13 @@ package services
 import (
 	"context"
 	"errors"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/llywelwyn/wow/internal/runner"
 	"github.com/llywelwyn/wow/internal/storage"
 )
 
@@ -177,3 +179,328 @@ func TestOpenerPropagatesRunnerErrors(t *testing.T) {
 		t.Fatalf("pager should not be called")
 	}
 }
+
+func TestOpenerFillsCommandPlaceholders(t *testing.T) {
+	opener, ctx, save, _, _ := setupOpener(t)
+
+	if err := save("urls/git", "https://example.com\n"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	opener.OpenFunc = runner.Run(`sh -c 'printf "%s|%s" "$1" "$2" > "`+out+`"' sh {key} {url}`, nil)
+
+	if err := opener.Open(ctx, "urls/git", OpenOptions{}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if string(got) != "urls/git|https://example.com" {
+		t.Fatalf("opener args = %q", got)
+	}
+}
+
+func TestOpenerRulesFirstMatchWins(t *testing.T) {
+	opener, ctx, save, open, _ := setupOpener(t)
+
+	markdown := &stubRunner{}
+	docs := &stubRunner{}
+	opener.Rules = []OpenRule{
+		{Name: "docs", Keys: []string{"docs/*"}, Open: docs.run},
+		{Name: "markdown", Keys: []string{"*.md"}, Open: markdown.run},
+	}
+
+	if err := save("notes/todo.md", "# todo"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+	if err := save("docs/readme.md", "# readme"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+
+	if err := opener.Open(ctx, "notes/todo.md", OpenOptions{}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	if err := opener.Open(ctx, "docs/readme.md", OpenOptions{}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+
+	if len(markdown.calledWith) != 1 || !strings.HasSuffix(markdown.calledWith[0], filepath.Join("notes", "todo.md")) {
+		t.Fatalf("markdown rule calls = %v", markdown.calledWith)
+	}
+	if len(docs.calledWith) != 1 {
+		t.Fatalf("docs rule calls = %v, want 1", docs.calledWith)
+	}
+	if len(open.calledWith) != 0 {
+		t.Fatalf("default opener should not be called")
+	}
+}
+
+func TestOpenerRulesMatchTypeAndLang(t *testing.T) {
+	opener, ctx, save, open, _ := setupOpener(t)
+
+	browser := &stubRunner{}
+	golang := &stubRunner{}
+	opener.Rules = []OpenRule{
+		{Name: "browser", Type: "url", Open: browser.run},
+		{Name: "go", Lang: "go", Open: golang.run},
+	}
+
+	if err := save("links/site", "https://example.com"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+	if err := save("snips/main.go", "package main"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+	if err := save("snips/plain", "text"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+
+	for _, k := range []string{"links/site", "snips/main.go", "snips/plain"} {
+		if err := opener.Open(ctx, k, OpenOptions{}); err != nil {
+			t.Fatalf("Open(%q) error = %v", k, err)
+		}
+	}
+
+	if len(browser.calledWith) != 1 || browser.calledWith[0] != "https://example.com" {
+		t.Fatalf("browser rule calls = %v", browser.calledWith)
+	}
+	if len(golang.calledWith) != 1 {
+		t.Fatalf("go rule calls = %v", golang.calledWith)
+	}
+	if len(open.calledWith) != 1 {
+		t.Fatalf("default opener calls = %v, want 1", open.calledWith)
+	}
+}
+
+func TestOpenerWithOverridesMatch(t *testing.T) {
+	opener, ctx, save, _, _ := setupOpener(t)
+
+	markdown := &stubRunner{}
+	raw := &stubRunner{}
+	opener.Rules = []OpenRule{
+		{Name: "markdown", Keys: []string{"*.md"}, Open: markdown.run},
+		{Name: "raw", Keys: []string{"*.txt"}, Open: raw.run},
+	}
+
+	if err := save("notes/todo.md", "# todo"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+
+	if err := opener.Open(ctx, "notes/todo.md", OpenOptions{With: "raw"}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	if len(raw.calledWith) != 1 || len(markdown.calledWith) != 0 {
+		t.Fatalf("raw calls = %v, markdown calls = %v", raw.calledWith, markdown.calledWith)
+	}
+
+	if err := opener.Open(ctx, "notes/todo.md", OpenOptions{With: "nope"}); !errors.Is(err, ErrUnknownRule) {
+		t.Fatalf("Open error = %v, want ErrUnknownRule", err)
+	}
+	if err := opener.Open(ctx, "notes/todo.md", OpenOptions{With: "raw", UsePager: true}); err == nil {
+		t.Fatalf("expected error combining pager and with")
+	}
+}
+
+func TestOpenerPlanExplainsMatch(t *testing.T) {
+	opener, ctx, save, open, _ := setupOpener(t)
+
+	markdown := &stubRunner{}
+	opener.Rules = []OpenRule{
+		{Name: "markdown", Keys: []string{"*.md"}, Command: "glow", Open: markdown.run},
+	}
+
+	if err := save("notes/todo.md", "# todo"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+	if err := save("notes/todo", "todo"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+
+	plan, err := opener.Plan(ctx, "notes/todo.md", OpenOptions{})
+	if err != nil {
+		t.Fatalf("Plan error = %v", err)
+	}
+	if plan.Rule != "markdown" || plan.Command != "glow" || plan.Reason != `key matches "*.md"` {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	plan, err = opener.Plan(ctx, "notes/todo", OpenOptions{})
+	if err != nil {
+		t.Fatalf("Plan error = %v", err)
+	}
+	if plan.Rule != "" || plan.Reason != "no rule matched" {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	if len(markdown.calledWith) != 0 || len(open.calledWith) != 0 {
+		t.Fatalf("Plan should not run anything")
+	}
+}
+
+func TestOpenerViewsUnmatchedMarkdown(t *testing.T) {
+	opener, ctx, save, open, _ := setupOpener(t)
+
+	view := &stubRunner{}
+	opener.ViewFunc = view.run
+
+	if err := save("notes/todo.md", "# todo"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+	if err := save("notes/todo", "todo"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+
+	if err := opener.Open(ctx, "notes/todo.md", OpenOptions{}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	if err := opener.Open(ctx, "notes/todo", OpenOptions{}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	if len(view.calledWith) != 1 || !strings.HasSuffix(view.calledWith[0], "todo.md") {
+		t.Fatalf("view calls = %v", view.calledWith)
+	}
+	if len(open.calledWith) != 1 || !strings.HasSuffix(open.calledWith[0], "todo") {
+		t.Fatalf("open calls = %v", open.calledWith)
+	}
+
+	if err := opener.Open(ctx, "notes/todo.md", OpenOptions{Raw: true}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	if len(view.calledWith) != 1 || len(open.calledWith) != 2 {
+		t.Fatalf("raw open: view calls = %v, open calls = %v", view.calledWith, open.calledWith)
+	}
+
+	// A matching rule still wins.
+	markdown := &stubRunner{}
+	opener.Rules = []OpenRule{{Name: "markdown", Keys: []string{"*.md"}, Open: markdown.run}}
+	plan, err := opener.Plan(ctx, "notes/todo.md", OpenOptions{})
+	if err != nil {
+		t.Fatalf("Plan error = %v", err)
+	}
+	if plan.View || plan.Rule != "markdown" {
+		t.Fatalf("plan = %+v", plan)
+	}
+}
+
+func TestOpenerRoutesBinaryByMediaType(t *testing.T) {
+	opener, ctx, save, open, _ := setupOpener(t)
+
+	system := &stubRunner{}
+	opener.SystemFunc = system.run
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	if err := save("img/logo", png); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+	if err := save("docs/spec", "%PDF-1.7\n\x00\xff"); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+
+	viewer := &stubRunner{}
+	opener.Rules = []OpenRule{{Name: "images", MIME: []string{"image/*"}, Open: viewer.run}}
+
+	plan, err := opener.Plan(ctx, "img/logo", OpenOptions{})
+	if err != nil {
+		t.Fatalf("Plan error = %v", err)
+	}
+	if plan.Rule != "images" || plan.Reason != `mime matches "image/*"` {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	if err := opener.Open(ctx, "docs/spec", OpenOptions{}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	if len(system.calledWith) != 1 || len(open.calledWith) != 0 {
+		t.Fatalf("system calls = %v, open calls = %v", system.calledWith, open.calledWith)
+	}
+
+	if err := opener.Open(ctx, "docs/spec", OpenOptions{UsePager: true}); err == nil {
+		t.Fatalf("expected error paging a binary snippet")
+	}
+}
+
+func TestOpenerGivesProgramsDecompressedCopy(t *testing.T) {
+	opener, ctx, _, _, _ := setupOpener(t)
+
+	content := strings.Repeat("level=info msg=ok\n", 200)
+	saver := &Saver{BaseDir: opener.BaseDir, DB: opener.DB, Now: time.Now, CompressAbove: 1}
+	res, err := saver.Save(ctx, SaveRequest{Key: "logs/app.log", Reader: strings.NewReader(content)})
+	if err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+	if res.Metadata.Encoding != storage.EncodingGzip {
+		t.Fatalf("Encoding = %q, want gzip", res.Metadata.Encoding)
+	}
+
+	var target, seen string
+	opener.OpenFunc = func(_ context.Context, path string) error {
+		data, err := os.ReadFile(path)
+		target, seen = path, string(data)
+		return err
+	}
+	if err := opener.Open(ctx, "logs/app.log", OpenOptions{At: Position{Line: 3}}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+
+	if seen != content {
+		t.Fatalf("program read %d bytes, want the %d decompressed", len(seen), len(content))
+	}
+	if filepath.Base(target) != "app.log" {
+		t.Fatalf("target = %q, want a copy named after the key", target)
+	}
+	if _, err := os.Stat(target); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("copy should be removed after the program exits, stat err = %v", err)
+	}
+}
+
+func TestOpenerSweepsStaleSystemCopies(t *testing.T) {
+	opener, ctx, _, _, _ := setupOpener(t)
+	opener.TempDir = t.TempDir()
+	system := &stubRunner{}
+	opener.SystemFunc = system.run
+
+	stale := filepath.Join(opener.TempDir, systemCopyPrefix+"stale")
+	if err := os.Mkdir(stale, 0o700); err != nil {
+		t.Fatalf("Mkdir error = %v", err)
+	}
+	old := time.Now().Add(-2 * systemCopyGrace)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatalf("Chtimes error = %v", err)
+	}
+	other := filepath.Join(opener.TempDir, "wow-edit-other")
+	if err := os.Mkdir(other, 0o700); err != nil {
+		t.Fatalf("Mkdir error = %v", err)
+	}
+	if err := os.Chtimes(other, old, old); err != nil {
+		t.Fatalf("Chtimes error = %v", err)
+	}
+
+	saver := &Saver{BaseDir: opener.BaseDir, DB: opener.DB, Now: time.Now, CompressAbove: 1}
+	content := "%PDF-1.7\n\x00\xff" + strings.Repeat("\x00", 200)
+	if _, err := saver.Save(ctx, SaveRequest{Key: "docs/spec", Reader: strings.NewReader(content)}); err != nil {
+		t.Fatalf("seed error = %v", err)
+	}
+	if err := opener.Open(ctx, "docs/spec", OpenOptions{}); err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+
+	if len(system.calledWith) != 1 {
+		t.Fatalf("system calls = %v", system.calledWith)
+	}
+	fresh := system.calledWith[0]
+	if data, err := os.ReadFile(fresh); err != nil || string(data) != content {
+		t.Fatalf("fresh copy should be kept for the program, read err = %v", err)
+	}
+	if filepath.Dir(filepath.Dir(fresh)) != opener.TempDir {
+		t.Fatalf("copy %q not under TempDir %q", fresh, opener.TempDir)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stale copy should be swept, stat err = %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("unrelated dir should be left alone, stat err = %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// ErrProtected indicates a change refused because the snippet is protected.
+var ErrProtected = errors.New("snippet is protected")
+
+// Protector marks snippets as protected, and clears the mark.
+//
+// Protected snippets are refused by edits, removal and tag changes
+// unless they are forced. Only the flag changes; the snippet keeps its
+// modified time.
+type Protector struct {
+	DB *sql.DB
+}
+
+// Protect protects the snippet rawKey names, or every snippet under it
+// as a prefix, and returns them in key order.
+func (p *Protector) Protect(ctx context.Context, rawKey string) ([]model.Metadata, error) {
+	return p.mark(ctx, rawKey, true)
+}
+
+// Unprotect clears the protection Protect set.
+func (p *Protector) Unprotect(ctx context.Context, rawKey string) ([]model.Metadata, error) {
+	return p.mark(ctx, rawKey, false)
+}
+
+func (p *Protector) mark(ctx context.Context, rawKey string, protected bool) ([]model.Metadata, error) {
+	if p.DB == nil {
+		return nil, errors.New("protector misconfigured")
+	}
+
+	matched, err := p.match(ctx, rawKey)
+	if err != nil {
+		return nil, err
+	}
+	for i, meta := range matched {
+		if meta.Protected == protected {
+			continue
+		}
+		meta.Protected = protected
+		if err := storage.UpdateMetadata(ctx, p.DB, meta); err != nil {
+			return nil, err
+		}
+		matched[i] = meta
+	}
+	return matched, nil
+}
+
+// match returns the snippet rawKey names when there is one, and
+// otherwise the snippets under it as a prefix.
+func (p *Protector) match(ctx context.Context, rawKey string) ([]model.Metadata, error) {
+	if normalized, err := key.Normalize(rawKey); err == nil {
+		meta, err := storage.GetMetadata(ctx, p.DB, normalized)
+		if err == nil {
+			return []model.Metadata{meta}, nil
+		}
+		if !errors.Is(err, storage.ErrMetadataNotFound) {
+			return nil, err
+		}
+	}
+
+	all, err := storage.ListMetadata(ctx, p.DB)
+	if err != nil {
+		return nil, err
+	}
+	// As for edits, a prefix covers whole segments: "ops" is not "opsec".
+	prefix := strings.TrimSuffix(rawKey, "/") + "/"
+	var matched []model.Metadata
+	for _, meta := range all {
+		if prefix != "/" && strings.HasPrefix(meta.Key, prefix) {
+			matched = append(matched, meta)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w: nothing matches %q", storage.ErrMetadataNotFound, rawKey)
+	}
+	slices.SortFunc(matched, func(a, b model.Metadata) int { return strings.Compare(a.Key, b.Key) })
+	return matched, nil
+}
+
+// refuseProtected returns ErrProtected for a protected snippet, unless
+// force is set.
+func refuseProtected(meta model.Metadata, force bool) error {
+	if meta.Protected && !force {
+		return fmt.Errorf("%w: %q; pass --force to change it anyway", ErrProtected, meta.Key)
+	}
+	return nil
+}
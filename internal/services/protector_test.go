@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func TestProtectorProtectsByKeyOrPrefix(t *testing.T) {
+	s, ctx := newTestSaver(t)
+	for _, k := range []string{"ops/deploy", "ops/rollback", "opsy", "ops-old", "notes"} {
+		if _, err := s.Save(ctx, SaveRequest{Key: k, Reader: strings.NewReader(k + "\n")}); err != nil {
+			t.Fatalf("Save(%s) error = %v", k, err)
+		}
+	}
+
+	p := &Protector{DB: s.DB}
+	marked, err := p.Protect(ctx, "ops/")
+	if err != nil {
+		t.Fatalf("Protect error = %v", err)
+	}
+	if len(marked) != 2 || marked[0].Key != "ops/deploy" || marked[1].Key != "ops/rollback" {
+		t.Fatalf("Protect(ops/) = %+v, want ops/deploy and ops/rollback", marked)
+	}
+	if marked, err = p.Protect(ctx, "ops"); err != nil || len(marked) != 2 {
+		t.Fatalf("Protect(ops) = %+v, %v, want only the snippets under ops/", marked, err)
+	}
+	if marked, err = p.Protect(ctx, "notes"); err != nil || len(marked) != 1 {
+		t.Fatalf("Protect(notes) = %+v, %v", marked, err)
+	}
+	if _, err := p.Protect(ctx, "nothing/"); !errors.Is(err, storage.ErrMetadataNotFound) {
+		t.Fatalf("Protect(nothing/) err = %v, want ErrMetadataNotFound", err)
+	}
+
+	for k, want := range map[string]bool{"ops/deploy": true, "ops/rollback": true, "opsy": false, "ops-old": false, "notes": true} {
+		meta, err := storage.GetMetadata(ctx, s.DB, k)
+		if err != nil {
+			t.Fatalf("GetMetadata(%s) error = %v", k, err)
+		}
+		if meta.Protected != want {
+			t.Errorf("%s Protected = %v, want %v", k, meta.Protected, want)
+		}
+	}
+
+	if _, err := p.Unprotect(ctx, "notes"); err != nil {
+		t.Fatalf("Unprotect error = %v", err)
+	}
+	if meta, _ := storage.GetMetadata(ctx, s.DB, "notes"); meta.Protected {
+		t.Fatal("notes still protected after Unprotect")
+	}
+}
+
+func TestProtectedSnippetsRefuseChangesWithoutForce(t *testing.T) {
+	s, ctx := newTestSaver(t)
+	if _, err := s.Save(ctx, SaveRequest{Key: "runbook", Reader: strings.NewReader("restart it\n")}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+	if _, err := (&Protector{DB: s.DB}).Protect(ctx, "runbook"); err != nil {
+		t.Fatalf("Protect error = %v", err)
+	}
+
+	meta := &Metadata{DB: s.DB, Now: func() time.Time { return time.Unix(1_700_000_100, 0) }}
+	if _, err := meta.UpdateTags(ctx, "runbook", []string{"ops"}, nil); !errors.Is(err, ErrProtected) {
+		t.Fatalf("UpdateTags err = %v, want ErrProtected", err)
+	}
+	if _, err := meta.SetType(ctx, "runbook", "script", ""); !errors.Is(err, ErrProtected) {
+		t.Fatalf("SetType err = %v, want ErrProtected", err)
+	}
+	editor := &Editor{BaseDir: s.BaseDir, DB: s.DB, Now: meta.Now, Open: func(context.Context, string) error {
+		t.Fatal("editor opened a protected snippet")
+		return nil
+	}}
+	if _, err := editor.Edit(ctx, "runbook", EditOptions{}); !errors.Is(err, ErrProtected) {
+		t.Fatalf("Edit err = %v, want ErrProtected", err)
+	}
+	remover := &Remover{BaseDir: s.BaseDir, DB: s.DB}
+	if err := remover.Remove(ctx, "runbook"); !errors.Is(err, ErrProtected) {
+		t.Fatalf("Remove err = %v, want ErrProtected", err)
+	}
+
+	pass := func() ([]byte, error) { return []byte("pass"), nil }
+	crypter := &Crypter{BaseDir: s.BaseDir, DB: s.DB, Passphrase: pass}
+	if _, err := crypter.Encrypt(ctx, "runbook"); !errors.Is(err, ErrProtected) {
+		t.Fatalf("Encrypt err = %v, want ErrProtected", err)
+	}
+
+	meta.Force = true
+	crypter.Force = true
+	if _, err := crypter.Encrypt(ctx, "runbook"); err != nil {
+		t.Fatalf("forced Encrypt error = %v", err)
+	}
+	result, err := meta.UpdateTags(ctx, "runbook", []string{"ops"}, nil)
+	if err != nil {
+		t.Fatalf("forced UpdateTags error = %v", err)
+	}
+	if !result.Metadata.Protected {
+		t.Fatal("forced UpdateTags cleared the protection")
+	}
+	remover.Force = true
+	if err := remover.Remove(ctx, "runbook"); err != nil {
+		t.Fatalf("forced Remove error = %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+)
+
+// RedactMask replaces each secret Redact finds. It is the same length
+// whatever it hides, and reads as plain text in Markdown too.
+const RedactMask = "[redacted]"
+
+// assignment matches "name = value" and "name: value" lines, with the
+// value as its second group.
+var assignment = regexp.MustCompile(`^(\s*(?:export\s+)?[A-Za-z_][\w.-]*\s*[:=]\s*)(\S.*?)\s*$`)
+
+// bareValue matches a line holding a single word, such as a token.
+var bareValue = regexp.MustCompile(`^\s*(\S+)\s*$`)
+
+// Redact returns a copy of data with what the rules match masked, for
+// display. Where a rule's pattern has a group named "secret", only that
+// group is masked. With tagged, for snippets tagged SecretTag, the
+// values of assignments and lines holding a single word are masked too.
+func (s *SecretScanner) Redact(data []byte, tagged bool) []byte {
+	var out bytes.Buffer
+	out.Grow(len(data))
+
+	var until *regexp.Regexp
+	for line := range bytes.Lines(data) {
+		body := bytes.TrimRight(line, "\r\n")
+		ending := line[len(body):]
+
+		if until != nil {
+			if until.Match(body) {
+				until = nil
+				out.Write(line)
+			} else {
+				out.WriteString(RedactMask)
+				out.Write(ending)
+			}
+			continue
+		}
+
+		out.Write(s.redactLine(body, tagged))
+		out.Write(ending)
+		for _, rule := range s.Rules {
+			if rule.Until != nil && rule.Pattern.Match(body) {
+				until = rule.Until
+			}
+		}
+	}
+	return out.Bytes()
+}
+
+// span is a masked range of a line.
+type span struct{ start, end int }
+
+func (s *SecretScanner) redactLine(line []byte, tagged bool) []byte {
+	var spans []span
+	for _, rule := range s.Rules {
+		group := rule.Pattern.SubexpIndex("secret")
+		for _, m := range rule.Pattern.FindAllSubmatchIndex(line, -1) {
+			if entropy(line[m[0]:m[1]]) < rule.MinEntropy {
+				continue
+			}
+			if group > 0 && m[2*group] >= 0 {
+				spans = append(spans, span{m[2*group], m[2*group+1]})
+				continue
+			}
+			if rule.Until == nil {
+				spans = append(spans, span{m[0], m[1]})
+			}
+		}
+	}
+	if tagged {
+		for _, re := range []*regexp.Regexp{assignment, bareValue} {
+			if m := re.FindSubmatchIndex(line); m != nil {
+				last := len(m) - 2
+				spans = append(spans, span{m[last], m[last+1]})
+				break
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return line
+	}
+
+	slices.SortFunc(spans, func(a, b span) int { return a.start - b.start })
+	var out []byte
+	at := 0
+	for _, sp := range spans {
+		if sp.end <= at {
+			continue
+		}
+		if sp.start >= at {
+			out = append(out, line[at:sp.start]...)
+			out = append(out, RedactMask...)
+		}
+		at = sp.end
+	}
+	return append(out, line[at:]...)
+}
+
+// HasSecretTag reports whether a snippet's comma-separated tags include
+// SecretTag.
+func HasSecretTag(tags string) bool {
+	return slices.Contains(parseTags(tags), SecretTag)
+}
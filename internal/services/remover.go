@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/llywelwyn/wow/internal/hooks"
 	"github.com/llywelwyn/wow/internal/key"
 	"github.com/llywelwyn/wow/internal/storage"
 )
@@ -13,6 +14,8 @@ import (
 type Remover struct {
 	BaseDir string
 	DB      *sql.DB
+	Hooks   *hooks.Runner
+	Force   bool // removes protected snippets too.
 }
 
 // Remove deletes the snippet identified by key, returning ErrMetadataNotFound when absent.
@@ -26,18 +29,30 @@ func (r *Remover) Remove(ctx context.Context, rawKey string) error {
 		return err
 	}
 
-	if err := storage.DeleteMetadata(ctx, r.DB, normalized); err != nil {
+	path, err := key.ResolvePath(r.BaseDir, normalized)
+	if err != nil {
 		return err
 	}
 
-	path, err := key.ResolvePath(r.BaseDir, normalized)
+	meta, err := storage.GetMetadata(ctx, r.DB, normalized)
 	if err != nil {
 		return err
 	}
+	if err := refuseProtected(meta, r.Force); err != nil {
+		return err
+	}
+
+	if err := r.Hooks.Run(ctx, hooks.NewPayload(hooks.PreRemove, path, meta)); err != nil {
+		return err
+	}
+
+	if err := storage.DeleteMetadata(ctx, r.DB, normalized); err != nil {
+		return err
+	}
 
 	if err := storage.Delete(path); err != nil && !errors.Is(err, storage.ErrNotFound) {
 		return err
 	}
 
-	return nil
+	return r.Hooks.Run(ctx, hooks.NewPayload(hooks.PostRemove, path, meta))
 }
@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/llywelwyn/wow/internal/hooks"
 	"github.com/llywelwyn/wow/internal/storage"
 )
 
@@ -64,3 +67,68 @@ func TestRemoverMissingKey(t *testing.T) {
 		t.Fatalf("expected ErrMetadataNotFound, got %v", err)
 	}
 }
+
+func TestRemoverHooksSeeSnippet(t *testing.T) {
+	base := t.TempDir()
+	db, err := storage.InitMetaDB(filepath.Join(base, "meta.db"))
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	saver := &Saver{BaseDir: base, DB: db, Now: func() time.Time { return time.Unix(1_700_000_000, 0) }}
+	ctx := context.Background()
+	if _, err := saver.Save(ctx, SaveRequest{Key: "go/foo", Reader: strings.NewReader("content")}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := "#!/bin/sh\necho \"$WOW_HOOK $WOW_KEY\" >> " + log + "\n"
+	for _, event := range []string{hooks.PreRemove, hooks.PostRemove} {
+		if err := os.WriteFile(filepath.Join(dir, event), []byte(script), 0o755); err != nil {
+			t.Fatalf("WriteFile error = %v", err)
+		}
+	}
+
+	remover := &Remover{BaseDir: base, DB: db, Hooks: &hooks.Runner{Dir: dir}}
+	if err := remover.Remove(ctx, "go/foo"); err != nil {
+		t.Fatalf("Remove error = %v", err)
+	}
+
+	got, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if string(got) != "pre-remove go/foo\npost-remove go/foo\n" {
+		t.Fatalf("hook log = %q", got)
+	}
+}
+
+func TestRemoverPreRemoveHookAborts(t *testing.T) {
+	base := t.TempDir()
+	db, err := storage.InitMetaDB(filepath.Join(base, "meta.db"))
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	saver := &Saver{BaseDir: base, DB: db, Now: func() time.Time { return time.Unix(1_700_000_000, 0) }}
+	ctx := context.Background()
+	if _, err := saver.Save(ctx, SaveRequest{Key: "go/foo", Reader: strings.NewReader("content")}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, hooks.PreRemove), []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	remover := &Remover{BaseDir: base, DB: db, Hooks: &hooks.Runner{Dir: dir}}
+	if err := remover.Remove(ctx, "go/foo"); !errors.Is(err, hooks.ErrAborted) {
+		t.Fatalf("Remove error = %v, want ErrAborted", err)
+	}
+	if _, err := storage.GetMetadata(ctx, db, "go/foo"); err != nil {
+		t.Fatalf("snippet should survive aborted remove, got %v", err)
+	}
+}
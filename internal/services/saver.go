@@ -1,15 +1,19 @@
 package services
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/llywelwyn/wow/internal/hooks"
 	"github.com/llywelwyn/wow/internal/key"
 	"github.com/llywelwyn/wow/internal/model"
 	"github.com/llywelwyn/wow/internal/storage"
@@ -18,19 +22,35 @@ import (
 // ErrSnippetExists indicates a save attempted to overwrite an existing snippet.
 var ErrSnippetExists = errors.New("snippet already exists")
 
+// ErrEmptySnippet indicates a save with no content.
+var ErrEmptySnippet = errors.New("snippet content is empty")
+
+// ErrDuplicateContent indicates a save refused because the same content
+// is already saved under another key.
+var ErrDuplicateContent = errors.New("identical content already saved")
+
+// ErrSnippetTooLarge indicates the content went over Saver.MaxSize.
+var ErrSnippetTooLarge = errors.New("snippet is too large")
+
 // SaveRequest captures the inputs required to persist a snippet.
 type SaveRequest struct {
 	Key         string
 	Description string
 	Tags        []string
 	Reader      io.Reader
+	Tee         io.Writer        // receives the content as it is read; nil for none.
+	Progress    func(read int64) // called as content is read; nil for none.
+	NoDupes     bool             // refuse content already saved under another key.
+	Encrypt     bool             // seal the content with Saver.Passphrase.
+	SkipScan    bool             // save without scanning for secrets.
 }
 
-// SaveResult returns the persisted key, metadata, and captured contents.
+// SaveResult returns the persisted key and metadata.
 type SaveResult struct {
-	Key      string
-	Metadata model.Metadata
-	Contents []byte
+	Key        string
+	Metadata   model.Metadata
+	Duplicates []string        // other keys already holding the same content.
+	Secrets    []SecretFinding // what secret scanning found, when its policy let the save go ahead.
 }
 
 // Saver coordinates saving snippet content and metadata.
@@ -38,9 +58,30 @@ type Saver struct {
 	BaseDir string
 	DB      *sql.DB
 	Now     func() time.Time
+	Hooks   *hooks.Runner
+	MaxSize int64 // largest content accepted, in bytes; zero for no limit.
+
+	// CompressAbove is the size from which content is stored gzipped,
+	// when that makes it smaller; zero never compresses.
+	CompressAbove int64
+
+	// Passphrase supplies the passphrase for encrypted saves.
+	Passphrase storage.Passphrase
+
+	// Secrets scans content that is not being encrypted, and its
+	// policy says what to do with content that looks secret; nil
+	// skips scanning.
+	Secrets *SecretScanner
 }
 
 // Save writes the snippet to disk and stores metadata, generating an auto key when absent.
+//
+// The content is streamed to a temp file beside the snippet and hashed
+// on the way, so it is never held in memory; only its start is kept to
+// detect the type. The file is moved into place once it is complete.
+//
+// Encrypted content is not compressed, and its recorded hash is that of
+// the sealed file. The plaintext hash is only used to find duplicates.
 func (s *Saver) Save(ctx context.Context, req SaveRequest) (SaveResult, error) {
 	if s.DB == nil || s.Now == nil {
 		return SaveResult{}, errors.New("saver misconfigured")
@@ -49,16 +90,6 @@ func (s *Saver) Save(ctx context.Context, req SaveRequest) (SaveResult, error) {
 		return SaveResult{}, errors.New("reader required")
 	}
 
-	payload, err := io.ReadAll(req.Reader)
-	if err != nil {
-		return SaveResult{}, fmt.Errorf("read input: %w", err)
-	}
-
-	if len(payload) == 0 {
-		return SaveResult{}, errors.New("snippet content is empty")
-	}
-
-	contentType := detectType(payload)
 	now := s.Now()
 
 	resolvedKey, err := s.resolveKey(req.Key, now)
@@ -79,17 +110,111 @@ func (s *Saver) Save(ctx context.Context, req SaveRequest) (SaveResult, error) {
 		return SaveResult{}, ErrSnippetExists
 	}
 
-	if err := storage.Save(path, bytes.NewReader(payload)); err != nil {
+	pending, err := storage.Create(path)
+	if err != nil {
 		return SaveResult{}, err
 	}
+	defer pending.Discard()
+	if req.Encrypt {
+		pending.ShredOnDiscard()
+	}
 
+	head := &headWriter{max: sniffLen}
+	sum := sha256.New()
+	writers := []io.Writer{pending, sum, head}
+	if req.Tee != nil {
+		writers = append(writers, req.Tee)
+	}
+	var scan *secretWriter
+	if s.Secrets != nil && s.Secrets.Policy != SecretsOff && !req.Encrypt && !req.SkipScan {
+		scan = s.Secrets.writer()
+		writers = append(writers, scan)
+	}
+
+	size, err := s.copy(io.MultiWriter(writers...), req.Reader, req.Progress)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	if size == 0 {
+		return SaveResult{}, ErrEmptySnippet
+	}
+
+	encrypt, tags := req.Encrypt, req.Tags
+	var secrets []SecretFinding
+	if scan != nil {
+		secrets = scan.done()
+	}
+	if len(secrets) > 0 {
+		switch s.Secrets.Policy {
+		case SecretsBlock:
+			return SaveResult{}, fmt.Errorf("%w: %s; save it with --encrypt, or with --no-scan if it is not secret",
+				ErrSecretFound, DescribeSecrets(secrets))
+		case SecretsEncrypt:
+			encrypt = true
+			pending.ShredOnDiscard()
+		default:
+			tags = append(slices.Clone(tags), SecretTag)
+		}
+	}
+
+	digest := hex.EncodeToString(sum.Sum(nil))
+	duplicates, err := storage.KeysWithSHA256(ctx, s.DB, digest)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	if len(duplicates) > 0 && req.NoDupes {
+		return SaveResult{}, fmt.Errorf("%w: same content as %s", ErrDuplicateContent, quoteKeys(duplicates))
+	}
+
+	detected := Detect(resolvedKey, head.buf)
 	meta := model.Metadata{
 		Key:         resolvedKey,
-		Type:        contentType,
+		Type:        detected.Type,
+		Language:    detected.Language,
+		MIME:        detected.MIME,
+		Size:        size,
+		SHA256:      digest,
 		Created:     now,
 		Modified:    now,
 		Description: req.Description,
-		Tags:        MergeTags("", req.Tags, nil),
+		Tags:        MergeTags("", tags, nil),
+	}
+
+	// The hook reads the content as written, before it is sealed or
+	// compressed and before anything is in place.
+	if err := s.Hooks.Run(ctx, hooks.NewPayload(hooks.PreSave, pending.Name(), meta)); err != nil {
+		return SaveResult{}, err
+	}
+
+	encoding := storage.EncodingNone
+	switch {
+	case encrypt:
+		if s.Passphrase == nil {
+			return SaveResult{}, errors.New("no passphrase to encrypt with")
+		}
+		pass, err := s.Passphrase()
+		if err != nil {
+			return SaveResult{}, err
+		}
+		if err := pending.Encrypt(pass); err != nil {
+			return SaveResult{}, err
+		}
+		encoding = storage.EncodingEncrypted
+	case s.CompressAbove > 0 && size >= s.CompressAbove:
+		if encoding, err = pending.Compress(); err != nil {
+			return SaveResult{}, err
+		}
+	}
+
+	meta.Encoding = encoding
+	if encrypt {
+		if meta.SHA256, err = pending.SHA256(); err != nil {
+			return SaveResult{}, err
+		}
+	}
+
+	if err := pending.Commit(); err != nil {
+		return SaveResult{}, err
 	}
 
 	if err := storage.InsertMetadata(ctx, s.DB, meta); err != nil {
@@ -100,13 +225,103 @@ func (s *Saver) Save(ctx context.Context, req SaveRequest) (SaveResult, error) {
 		return SaveResult{}, err
 	}
 
+	if err := s.Hooks.Run(ctx, hooks.NewPayload(hooks.PostSave, path, meta)); err != nil {
+		return SaveResult{}, err
+	}
+
 	return SaveResult{
-		Key:      resolvedKey,
-		Metadata: meta,
-		Contents: payload,
+		Key:        resolvedKey,
+		Metadata:   meta,
+		Duplicates: duplicates,
+		Secrets:    secrets,
 	}, nil
 }
 
+// quoteKeys lists keys for messages, e.g. "a", "b" and "c".
+func quoteKeys(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = strconv.Quote(k)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
+}
+
+// writeContent replaces the snippet file at path with content,
+// sealed with pass when one is given, or else compressed when it is at
+// least compressAbove bytes. It returns the encoding the file is
+// stored in.
+func writeContent(path string, content []byte, compressAbove int64, pass []byte) (string, error) {
+	pending, err := storage.Create(path)
+	if err != nil {
+		return storage.EncodingNone, err
+	}
+	defer pending.Discard()
+	if pass != nil {
+		pending.ShredOnDiscard()
+	}
+
+	if _, err := pending.Write(content); err != nil {
+		return storage.EncodingNone, fmt.Errorf("write temp file: %w", err)
+	}
+	encoding := storage.EncodingNone
+	switch {
+	case pass != nil:
+		if err := pending.Encrypt(pass); err != nil {
+			return storage.EncodingNone, err
+		}
+		encoding = storage.EncodingEncrypted
+	case compressAbove > 0 && int64(len(content)) >= compressAbove:
+		if encoding, err = pending.Compress(); err != nil {
+			return storage.EncodingNone, err
+		}
+	}
+	return encoding, pending.Commit()
+}
+
+// copy streams src to dst, stopping with ErrSnippetTooLarge before
+// writing anything past MaxSize.
+func (s *Saver) copy(dst io.Writer, src io.Reader, progress func(int64)) (int64, error) {
+	buf := make([]byte, 32<<10)
+	var written int64
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if s.MaxSize > 0 && written+int64(n) > s.MaxSize {
+				return written, fmt.Errorf("%w: over the limit of %d bytes set by max_snippet_size", ErrSnippetTooLarge, s.MaxSize)
+			}
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return written, fmt.Errorf("write snippet: %w", err)
+			}
+			written += int64(n)
+			if progress != nil {
+				progress(written)
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, fmt.Errorf("read input: %w", readErr)
+		}
+	}
+}
+
+// headWriter keeps the first max bytes written to it.
+type headWriter struct {
+	buf []byte
+	max int
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if room := h.max - len(h.buf); room > 0 {
+		h.buf = append(h.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
 func (s *Saver) resolveKey(rawKey string, now time.Time) (string, error) {
 	if strings.TrimSpace(rawKey) != "" {
 		return rawKey, nil
@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/llywelwyn/wow/internal/hooks"
 	"github.com/llywelwyn/wow/internal/storage"
 )
 
@@ -59,8 +62,8 @@ func TestSaverSaveExplicitKey(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetMetadata error = %v", err)
 	}
-	if meta.Type != "text" {
-		t.Fatalf("metadata type = %q, want text", meta.Type)
+	if meta.Type != "code" || meta.Language != "go" {
+		t.Fatalf("metadata type = %q, language = %q, want code, go", meta.Type, meta.Language)
 	}
 	if meta.Tags != "go,utils" {
 		t.Fatalf("tags = %q, want go,utils", meta.Tags)
@@ -141,3 +144,163 @@ func TestSaverEmptyInput(t *testing.T) {
 		t.Fatalf("expected error for empty content")
 	}
 }
+
+func TestSaverPreSaveHookAborts(t *testing.T) {
+	s, ctx := newTestSaver(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, hooks.PreSave), []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	s.Hooks = &hooks.Runner{Dir: dir}
+
+	_, err := s.Save(ctx, SaveRequest{
+		Key:    "go/foo",
+		Reader: strings.NewReader("package main\n"),
+	})
+	if !errors.Is(err, hooks.ErrAborted) {
+		t.Fatalf("Save error = %v, want ErrAborted", err)
+	}
+
+	if exists, _ := storage.Exists(filepath.Join(s.BaseDir, "go", "foo")); exists {
+		t.Fatalf("snippet should not be written when pre-save fails")
+	}
+	if _, err := storage.GetMetadata(ctx, s.DB, "go/foo"); !errors.Is(err, storage.ErrMetadataNotFound) {
+		t.Fatalf("metadata should not be stored when pre-save fails, got %v", err)
+	}
+}
+
+func TestSaverPreSaveHookReadsContent(t *testing.T) {
+	s, ctx := newTestSaver(t)
+	s.CompressAbove = 1
+
+	dir := t.TempDir()
+	seen := filepath.Join(dir, "seen")
+	hook := "#!/bin/sh\ncat \"$WOW_FILE\" > " + seen + " || exit 2\n! grep -q FIXME \"$WOW_FILE\"\n"
+	if err := os.WriteFile(filepath.Join(dir, hooks.PreSave), []byte(hook), 0o755); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	s.Hooks = &hooks.Runner{Dir: dir}
+
+	content := strings.Repeat("echo lint me\n", 20)
+	if _, err := s.Save(ctx, SaveRequest{Key: "sh/clean", Reader: strings.NewReader(content)}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+	got, err := os.ReadFile(seen)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if string(got) != content {
+		t.Fatalf("pre-save hook read %q, want %q", got, content)
+	}
+
+	_, err = s.Save(ctx, SaveRequest{Key: "sh/dirty", Reader: strings.NewReader("# FIXME\n")})
+	if !errors.Is(err, hooks.ErrAborted) {
+		t.Fatalf("Save error = %v, want ErrAborted", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(s.BaseDir, "sh"))
+	if err != nil {
+		t.Fatalf("ReadDir error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "clean" {
+		t.Fatalf("files after an aborted save = %v, want only clean", entries)
+	}
+}
+
+func TestSaverMaxSizeLeavesNothingBehind(t *testing.T) {
+	s, ctx := newTestSaver(t)
+	s.MaxSize = 10
+
+	_, err := s.Save(ctx, SaveRequest{
+		Key:    "big/foo",
+		Reader: strings.NewReader("more than ten bytes"),
+	})
+	if !errors.Is(err, ErrSnippetTooLarge) {
+		t.Fatalf("Save error = %v, want ErrSnippetTooLarge", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.BaseDir, "big"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("ReadDir error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("files left behind: %v", entries)
+	}
+	if _, err := storage.GetMetadata(ctx, s.DB, "big/foo"); !errors.Is(err, storage.ErrMetadataNotFound) {
+		t.Fatalf("metadata should not be stored, got %v", err)
+	}
+
+	s.MaxSize = int64(len("exactly ten"))
+	if _, err := s.Save(ctx, SaveRequest{Key: "big/foo", Reader: strings.NewReader("exactly ten")}); err != nil {
+		t.Fatalf("Save at the limit error = %v", err)
+	}
+}
+
+func TestSaverStreamsLargeInput(t *testing.T) {
+	s, ctx := newTestSaver(t)
+
+	content := "package main\n" + strings.Repeat("// filler line\n", 4096)
+	var tee strings.Builder
+	var read int64
+	res, err := s.Save(ctx, SaveRequest{
+		Key:      "go/big",
+		Reader:   strings.NewReader(content),
+		Tee:      &tee,
+		Progress: func(n int64) { read = n },
+	})
+	if err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	if tee.String() != content {
+		t.Fatalf("tee got %d bytes, want %d", tee.Len(), len(content))
+	}
+	if read != int64(len(content)) {
+		t.Fatalf("progress read = %d, want %d", read, len(content))
+	}
+	if res.Metadata.Size != int64(len(content)) {
+		t.Fatalf("Size = %d, want %d", res.Metadata.Size, len(content))
+	}
+	if res.Metadata.Language != "go" {
+		t.Fatalf("Language = %q, want go", res.Metadata.Language)
+	}
+	if len(res.Metadata.SHA256) != 64 {
+		t.Fatalf("SHA256 = %q, want a hex digest", res.Metadata.SHA256)
+	}
+
+	data, err := storage.Read(filepath.Join(s.BaseDir, "go", "big"))
+	if err != nil {
+		t.Fatalf("Read error = %v", err)
+	}
+	if string(data) != content {
+		t.Fatalf("stored %d bytes, want %d", len(data), len(content))
+	}
+}
+
+func TestSaverReportsDuplicateContent(t *testing.T) {
+	s, ctx := newTestSaver(t)
+
+	content := "kubectl get pods -A\n"
+	if _, err := s.Save(ctx, SaveRequest{Key: "k8s/pods", Reader: strings.NewReader(content)}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	res, err := s.Save(ctx, SaveRequest{Key: "kube/pods", Reader: strings.NewReader(content)})
+	if err != nil {
+		t.Fatalf("second Save error = %v", err)
+	}
+	if len(res.Duplicates) != 1 || res.Duplicates[0] != "k8s/pods" {
+		t.Fatalf("Duplicates = %v, want [k8s/pods]", res.Duplicates)
+	}
+
+	_, err = s.Save(ctx, SaveRequest{Key: "misc/pods", Reader: strings.NewReader(content), NoDupes: true})
+	if !errors.Is(err, ErrDuplicateContent) {
+		t.Fatalf("Save with NoDupes error = %v, want ErrDuplicateContent", err)
+	}
+	if !strings.Contains(err.Error(), `"k8s/pods" and "kube/pods"`) {
+		t.Fatalf("error = %q, want it to name both keys", err)
+	}
+	if exists, _ := storage.Exists(filepath.Join(s.BaseDir, "misc", "pods")); exists {
+		t.Fatalf("refused duplicate should not be written")
+	}
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// ErrSecretFound indicates content refused by the SecretsBlock policy.
+var ErrSecretFound = errors.New("content looks like it holds a secret")
+
+// SecretTag is the tag SecretsWarn gives snippets that look secret.
+const SecretTag = "secret"
+
+// SecretPolicy is what saving does with content that looks like it
+// holds secrets.
+type SecretPolicy int
+
+const (
+	// SecretsWarn saves the content tagged "secret", and reports it.
+	SecretsWarn SecretPolicy = iota
+	// SecretsBlock refuses the content.
+	SecretsBlock
+	// SecretsEncrypt saves the content encrypted.
+	SecretsEncrypt
+	// SecretsOff saves without scanning.
+	SecretsOff
+)
+
+var secretPolicyNames = []string{"warn", "block", "encrypt", "off"}
+
+func (p SecretPolicy) String() string {
+	if p >= 0 && int(p) < len(secretPolicyNames) {
+		return secretPolicyNames[p]
+	}
+	return fmt.Sprintf("SecretPolicy(%d)", int(p))
+}
+
+// ParseSecretPolicy reads a policy by name; empty means SecretsWarn.
+func ParseSecretPolicy(name string) (SecretPolicy, error) {
+	if name == "" {
+		return SecretsWarn, nil
+	}
+	if i := slices.Index(secretPolicyNames, strings.ToLower(name)); i >= 0 {
+		return SecretPolicy(i), nil
+	}
+	return SecretsWarn, fmt.Errorf("unknown secrets policy %q; want one of %s", name, strings.Join(secretPolicyNames, ", "))
+}
+
+// SecretRule is a pattern that marks a line as holding a secret.
+type SecretRule struct {
+	Name       string
+	Pattern    *regexp.Regexp
+	MinEntropy float64 // bits per character a match needs, to skip ordinary words; zero for any.
+
+	// Until, if set, ends a secret spanning lines from the one Pattern
+	// matches; Redact masks the lines between them.
+	Until *regexp.Regexp
+}
+
+// DefaultSecretRules are the rules scanning starts with.
+var DefaultSecretRules = []SecretRule{
+	{Name: "private key", Pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`), Until: regexp.MustCompile(`-----END (?:[A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)},
+	{Name: "AWS access key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`)},
+	{Name: "Google API key", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{Name: "GitHub token", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[0-9A-Za-z]{36,}|github_pat_[0-9A-Za-z_]{60,})\b`)},
+	{Name: "Slack token", Pattern: regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z-]{10,}`)},
+	{Name: "JWT", Pattern: regexp.MustCompile(`\beyJ[0-9A-Za-z_-]{8,}\.eyJ[0-9A-Za-z_-]{8,}\.[0-9A-Za-z_-]{8,}`)},
+	{Name: "password", Pattern: regexp.MustCompile(`(?i)(?:\b|_)(?:password|passwd|pwd|secret|api_?key|access_?token|auth_?token)\b["']?\s*[:=]\s*["']?(?P<secret>[^\s"'$<{][^\s"']{3,})`)},
+	{Name: "high-entropy token", Pattern: regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`), MinEntropy: 4.3},
+}
+
+// SecretFinding is a rule that matched, and the line it matched on.
+type SecretFinding struct {
+	Rule string
+	Line int // counting from 1.
+}
+
+// SecretScanner looks for secrets in content, line by line.
+type SecretScanner struct {
+	Rules  []SecretRule
+	Policy SecretPolicy
+}
+
+// Scan returns what the rules find in data, in line order.
+func (s *SecretScanner) Scan(data []byte) []SecretFinding {
+	w := s.writer()
+	_, _ = w.Write(data)
+	return w.done()
+}
+
+// maxScanLine caps how much of a line is kept for scanning, so content
+// without newlines is not held in memory whole.
+const maxScanLine = 64 << 10
+
+// secretWriter scans content line by line as it is written.
+type secretWriter struct {
+	rules    []SecretRule
+	line     []byte
+	number   int
+	findings []SecretFinding
+}
+
+func (s *SecretScanner) writer() *secretWriter {
+	return &secretWriter{rules: s.Rules}
+}
+
+func (w *secretWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			break
+		}
+		w.keep(p[:i])
+		w.scan()
+		p = p[i+1:]
+	}
+	w.keep(p)
+	return n, nil
+}
+
+func (w *secretWriter) keep(p []byte) {
+	if room := maxScanLine - len(w.line); room > 0 {
+		w.line = append(w.line, p[:min(room, len(p))]...)
+	}
+}
+
+func (w *secretWriter) scan() {
+	w.number++
+	for _, rule := range w.rules {
+		for _, match := range rule.Pattern.FindAll(w.line, -1) {
+			if entropy(match) >= rule.MinEntropy {
+				w.findings = append(w.findings, SecretFinding{Rule: rule.Name, Line: w.number})
+				break
+			}
+		}
+	}
+	w.line = w.line[:0]
+}
+
+// done scans the last line, when it has no newline, and returns the findings.
+func (w *secretWriter) done() []SecretFinding {
+	if len(w.line) > 0 {
+		w.scan()
+	}
+	return w.findings
+}
+
+// entropy returns the Shannon entropy of b, in bits per byte.
+func entropy(b []byte) float64 {
+	if len(b) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, c := range b {
+		counts[c]++
+	}
+	var bits float64
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(b))
+			bits -= p * math.Log2(p)
+		}
+	}
+	return bits
+}
+
+// DescribeSecrets lists findings by rule for messages, e.g.
+// "AWS access key on line 3, JWT on lines 4 and 9".
+func DescribeSecrets(findings []SecretFinding) string {
+	var rules []string
+	lines := make(map[string][]string)
+	for _, f := range findings {
+		if _, ok := lines[f.Rule]; !ok {
+			rules = append(rules, f.Rule)
+		}
+		lines[f.Rule] = append(lines[f.Rule], strconv.Itoa(f.Line))
+	}
+
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		numbers := lines[rule]
+		if len(numbers) == 1 {
+			parts[i] = rule + " on line " + numbers[0]
+			continue
+		}
+		parts[i] = rule + " on lines " + strings.Join(numbers[:len(numbers)-1], ", ") + " and " + numbers[len(numbers)-1]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AuditResult reports what scanning one stored snippet found.
+type AuditResult struct {
+	Key       string
+	Findings  []SecretFinding
+	Encrypted bool  // skipped, as its content is already encrypted.
+	Err       error // why the snippet could not be read.
+}
+
+// SecretAuditor scans the snippets already in a vault.
+type SecretAuditor struct {
+	BaseDir string
+	DB      *sql.DB
+	Scanner *SecretScanner
+}
+
+// Audit scans every snippet whose key starts with prefix, in key order.
+// The scanner's policy is not applied.
+func (a *SecretAuditor) Audit(ctx context.Context, prefix string) ([]AuditResult, error) {
+	if a.DB == nil || a.Scanner == nil {
+		return nil, errors.New("secret auditor misconfigured")
+	}
+
+	entries, err := storage.ListMetadata(ctx, a.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []AuditResult
+	for _, meta := range entries {
+		if !strings.HasPrefix(meta.Key, prefix) {
+			continue
+		}
+		res := AuditResult{Key: meta.Key, Encrypted: meta.Encoding == storage.EncodingEncrypted}
+		if !res.Encrypted {
+			res.Findings, res.Err = a.scan(meta.Key, meta.Encoding)
+		}
+		results = append(results, res)
+	}
+
+	slices.SortFunc(results, func(a, b AuditResult) int { return strings.Compare(a.Key, b.Key) })
+	return results, nil
+}
+
+func (a *SecretAuditor) scan(k, encoding string) ([]SecretFinding, error) {
+	path, err := key.ResolvePath(a.BaseDir, k)
+	if err != nil {
+		return nil, err
+	}
+	r, err := storage.Open(path, encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	w := a.Scanner.writer()
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, fmt.Errorf("read snippet file: %w", err)
+	}
+	return w.done(), nil
+}
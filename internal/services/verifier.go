@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// Integrity is what verifying a snippet file found.
+type Integrity int
+
+const (
+	// IntegrityOK means the file matches its recorded hash.
+	IntegrityOK Integrity = iota
+	// IntegrityModified means the file was changed outside wow.
+	IntegrityModified
+	// IntegrityTruncated means the file holds less than was saved.
+	IntegrityTruncated
+	// IntegrityUnreadable means the file exists but cannot be read or decoded.
+	IntegrityUnreadable
+	// IntegrityMissing means the file is gone.
+	IntegrityMissing
+	// IntegrityUnrecorded means no hash was recorded to compare with.
+	IntegrityUnrecorded
+)
+
+func (i Integrity) String() string {
+	switch i {
+	case IntegrityOK:
+		return "ok"
+	case IntegrityModified:
+		return "modified"
+	case IntegrityTruncated:
+		return "truncated"
+	case IntegrityUnreadable:
+		return "unreadable"
+	case IntegrityMissing:
+		return "missing"
+	case IntegrityUnrecorded:
+		return "unrecorded"
+	default:
+		return fmt.Sprintf("Integrity(%d)", int(i))
+	}
+}
+
+// VerifyResult reports the integrity of one snippet.
+type VerifyResult struct {
+	Key       string
+	Integrity Integrity
+	Err       error // why the file could not be read in full.
+	Accepted  bool  // the file was adopted as the snippet's content.
+}
+
+// Acceptable reports whether the file differs from what was saved but
+// was read in full, so it can be adopted as the snippet's content.
+func (r VerifyResult) Acceptable() bool {
+	switch r.Integrity {
+	case IntegrityModified, IntegrityTruncated, IntegrityUnrecorded:
+		return r.Err == nil
+	default:
+		return false
+	}
+}
+
+// Verifier checks snippet files against the hashes in their metadata.
+type Verifier struct {
+	BaseDir string
+	DB      *sql.DB
+	Now     func() time.Time
+}
+
+// Verify checks every snippet whose key starts with prefix, in key
+// order. With accept, files that were changed but can be read are
+// adopted: their hash, size and media type are recorded anew. Only
+// the hash of encrypted snippets is, as they are checked as stored.
+func (v *Verifier) Verify(ctx context.Context, prefix string, accept bool) ([]VerifyResult, error) {
+	if v.DB == nil || v.Now == nil {
+		return nil, errors.New("verifier misconfigured")
+	}
+
+	entries, err := storage.ListMetadata(ctx, v.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []VerifyResult
+	for _, meta := range entries {
+		if !strings.HasPrefix(meta.Key, prefix) {
+			continue
+		}
+		res, found := v.check(meta)
+		if accept && res.Acceptable() {
+			updated := meta
+			updated.SHA256 = found.sum
+			if meta.Encoding != storage.EncodingEncrypted {
+				updated.Size, updated.MIME = found.size, Detect(meta.Key, found.head).MIME
+			}
+			updated.Modified = v.Now().UTC()
+			if err := storage.UpdateMetadata(ctx, v.DB, updated); err != nil {
+				return nil, fmt.Errorf("accept %q: %w", meta.Key, err)
+			}
+			res.Accepted = true
+		}
+		results = append(results, res)
+	}
+
+	slices.SortFunc(results, func(a, b VerifyResult) int { return strings.Compare(a.Key, b.Key) })
+	return results, nil
+}
+
+// onDisk is what was read from a snippet file.
+type onDisk struct {
+	sum  string
+	size int64
+	head []byte
+}
+
+func (v *Verifier) check(meta model.Metadata) (VerifyResult, onDisk) {
+	res := VerifyResult{Key: meta.Key}
+
+	path, err := key.ResolvePath(v.BaseDir, meta.Key)
+	if err != nil {
+		res.Integrity, res.Err = IntegrityUnreadable, err
+		return res, onDisk{}
+	}
+
+	r, err := storage.Open(path, storage.HashedEncoding(meta.Encoding))
+	if errors.Is(err, storage.ErrNotFound) {
+		res.Integrity = IntegrityMissing
+		return res, onDisk{}
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		// A compressed file cut off inside its header.
+		res.Integrity, res.Err = IntegrityTruncated, err
+		return res, onDisk{}
+	}
+	if err != nil {
+		res.Integrity, res.Err = IntegrityUnreadable, err
+		return res, onDisk{}
+	}
+	defer r.Close()
+
+	sum := sha256.New()
+	head := &headWriter{max: sniffLen}
+	size, err := io.Copy(io.MultiWriter(sum, head), r)
+	found := onDisk{sum: hex.EncodeToString(sum.Sum(nil)), size: size, head: head.buf}
+
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		res.Integrity, res.Err = IntegrityTruncated, err
+	case err != nil:
+		res.Integrity, res.Err = IntegrityUnreadable, err
+	case meta.SHA256 == "":
+		res.Integrity = IntegrityUnrecorded
+	case found.sum == meta.SHA256:
+		res.Integrity = IntegrityOK
+	case size < meta.Size && meta.Encoding != storage.EncodingEncrypted:
+		// The size of encrypted snippets is that of their plaintext.
+		res.Integrity = IntegrityTruncated
+	default:
+		res.Integrity = IntegrityModified
+	}
+	return res, found
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+func TestVerifierReportsEachProblem(t *testing.T) {
+	s, ctx := newTestSaver(t)
+
+	content := "line one\nline two\nline three\n"
+	for _, k := range []string{"a/ok", "a/edited", "a/cut", "b/gone", "b/packed", "c/skipped"} {
+		if _, err := s.Save(ctx, SaveRequest{Key: k, Reader: strings.NewReader(content + k)}); err != nil {
+			t.Fatalf("Save(%s) error = %v", k, err)
+		}
+	}
+	s.CompressAbove = 1
+	if _, err := s.Save(ctx, SaveRequest{Key: "b/zipped", Reader: strings.NewReader(strings.Repeat(content, 50))}); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	file := func(k string) string { return filepath.Join(s.BaseDir, filepath.FromSlash(k)) }
+	if err := os.WriteFile(file("a/edited"), []byte(content+"a/EDITED"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	if err := os.WriteFile(file("a/cut"), []byte("line one\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	if err := os.Remove(file("b/gone")); err != nil {
+		t.Fatalf("Remove error = %v", err)
+	}
+	if err := os.WriteFile(file("b/packed"), []byte("this was never gzipped at all"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	packed, _ := storage.GetMetadata(ctx, s.DB, "b/packed")
+	packed.Encoding = storage.EncodingGzip
+	if err := storage.UpdateMetadata(ctx, s.DB, packed); err != nil {
+		t.Fatalf("UpdateMetadata error = %v", err)
+	}
+	zipped, err := os.ReadFile(file("b/zipped"))
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if err := os.WriteFile(file("b/zipped"), zipped[:len(zipped)/2], 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	v := &Verifier{BaseDir: s.BaseDir, DB: s.DB, Now: s.Now}
+	results, err := v.Verify(ctx, "", false)
+	if err != nil {
+		t.Fatalf("Verify error = %v", err)
+	}
+
+	want := map[string]Integrity{
+		"a/cut":     IntegrityTruncated,
+		"a/edited":  IntegrityModified,
+		"a/ok":      IntegrityOK,
+		"b/gone":    IntegrityMissing,
+		"b/packed":  IntegrityUnreadable,
+		"b/zipped":  IntegrityTruncated,
+		"c/skipped": IntegrityOK,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %d", results, len(want))
+	}
+	for i, res := range results {
+		if i > 0 && results[i-1].Key > res.Key {
+			t.Fatalf("results out of key order: %q before %q", results[i-1].Key, res.Key)
+		}
+		if res.Integrity != want[res.Key] {
+			t.Fatalf("%s = %v, want %v", res.Key, res.Integrity, want[res.Key])
+		}
+	}
+
+	accepted, err := v.Verify(ctx, "a/", true)
+	if err != nil {
+		t.Fatalf("Verify accept error = %v", err)
+	}
+	if len(accepted) != 3 {
+		t.Fatalf("results under a/ = %+v, want 3", accepted)
+	}
+	for _, res := range accepted {
+		if res.Accepted != (res.Integrity != IntegrityOK) {
+			t.Fatalf("%s accepted = %v with integrity %v", res.Key, res.Accepted, res.Integrity)
+		}
+	}
+
+	again, err := v.Verify(ctx, "a/", false)
+	if err != nil {
+		t.Fatalf("Verify error = %v", err)
+	}
+	for _, res := range again {
+		if res.Integrity != IntegrityOK {
+			t.Fatalf("%s = %v after accepting, want ok", res.Key, res.Integrity)
+		}
+	}
+	if meta, _ := storage.GetMetadata(ctx, s.DB, "a/cut"); meta.Size != int64(len("line one\n")) {
+		t.Fatalf("accepted Size = %d, want the size on disk", meta.Size)
+	}
+
+	zippedResult, err := v.Verify(ctx, "b/zipped", true)
+	if err != nil {
+		t.Fatalf("Verify error = %v", err)
+	}
+	if zippedResult[0].Accepted {
+		t.Fatalf("a cut-off gzip stream should not be accepted")
+	}
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrEncrypted is returned when an encrypted snippet is read without a
+// passphrase.
+var ErrEncrypted = errors.New("snippet is encrypted")
+
+// ErrDecrypt is returned when an encrypted snippet cannot be opened,
+// either because the passphrase is wrong or the file was damaged.
+// Authenticated encryption cannot tell the two apart.
+var ErrDecrypt = errors.New("wrong passphrase, or the encrypted snippet is damaged")
+
+// Passphrase returns the passphrase encrypted snippets are sealed with.
+// It is only called when a snippet is actually encrypted.
+type Passphrase func() ([]byte, error)
+
+// Sealed files start with sealMagic, a byte giving scrypt's cost as a
+// power of two, the salt and the nonce. The rest is AES-256-GCM
+// ciphertext, authenticated together with that header.
+const (
+	sealMagic   = "wowseal1"
+	sealLogN    = 15
+	sealSaltLen = 16
+	sealHeader  = len(sealMagic) + 1 + sealSaltLen
+)
+
+// Encrypt seals plaintext with a key derived from passphrase.
+func Encrypt(plaintext, passphrase []byte) ([]byte, error) {
+	header := make([]byte, sealHeader, sealHeader+12)
+	copy(header, sealMagic)
+	header[len(sealMagic)] = sealLogN
+	if _, err := rand.Read(header[len(sealMagic)+1:]); err != nil {
+		return nil, fmt.Errorf("encrypt snippet: %w", err)
+	}
+
+	aead, err := sealCipher(passphrase, header)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("encrypt snippet: %w", err)
+	}
+	header = append(header, nonce...)
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// Decrypt opens content sealed by Encrypt.
+func Decrypt(sealed, passphrase []byte) ([]byte, error) {
+	if len(sealed) < sealHeader || !bytes.HasPrefix(sealed, []byte(sealMagic)) {
+		return nil, ErrDecrypt
+	}
+	aead, err := sealCipher(passphrase, sealed[:sealHeader])
+	if err != nil {
+		return nil, err
+	}
+	end := sealHeader + aead.NonceSize()
+	if len(sealed) < end {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, sealed[sealHeader:end], sealed[end:], sealed[:end])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// sealCipher derives the key for a sealed file from its header.
+func sealCipher(passphrase, header []byte) (cipher.AEAD, error) {
+	logN := header[len(sealMagic)]
+	if logN < 10 || logN > 20 {
+		return nil, ErrDecrypt
+	}
+	salt := header[len(sealMagic)+1 : sealHeader]
+	key, err := scrypt.Key(passphrase, salt, 1<<logN, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadDecrypted is ReadEncoded for snippets that may be encrypted,
+// asking passphrase for the passphrase when one is.
+func ReadDecrypted(path, encoding string, passphrase Passphrase) ([]byte, error) {
+	if encoding != EncodingEncrypted {
+		return ReadEncoded(path, encoding)
+	}
+	if passphrase == nil {
+		return nil, ErrEncrypted
+	}
+	sealed, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(sealed, pass)
+}
+
+// Encrypt seals what has been written so far with passphrase. The
+// plaintext temp file is shredded. Nothing more may be written
+// afterwards.
+func (p *Pending) Encrypt(passphrase []byte) error {
+	if p.done {
+		return errors.New("snippet file already committed or discarded")
+	}
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek temp file: %w", err)
+	}
+	plaintext, err := io.ReadAll(p.file)
+	if err != nil {
+		return fmt.Errorf("read temp file: %w", err)
+	}
+	sealed, err := Encrypt(plaintext, passphrase)
+	clear(plaintext)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := createTemp(filepath.Dir(p.path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(sealed); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	_ = p.file.Close()
+	_ = Shred(p.file.Name())
+	p.file = tmp
+	return nil
+}
+
+// Shred overwrites the file at path with zeros before removing it, so
+// plaintext copies of encrypted snippets do not linger in free space.
+// Filesystems that copy on write or journal data may still keep the
+// old blocks; this is a best effort.
+func Shred(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("shred %q: %w", path, err)
+	}
+	info, err := f.Stat()
+	if err == nil {
+		_, err = io.CopyN(f, zeros{}, info.Size())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(path); err == nil {
+		err = removeErr
+	}
+	if err != nil {
+		return fmt.Errorf("shred %q: %w", path, err)
+	}
+	return nil
+}
+
+// zeros reads as an endless run of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	plaintext := []byte("postgres://app:hunter2@db/prod\n")
+	sealed, err := Encrypt(plaintext, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Encrypt error = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("hunter2")) {
+		t.Fatalf("sealed content holds the plaintext: %q", sealed)
+	}
+
+	got, err := Decrypt(sealed, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Decrypt error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt = %q, want %q", got, plaintext)
+	}
+
+	if _, err := Decrypt(sealed, []byte("battery staple")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("Decrypt with wrong passphrase err = %v, want ErrDecrypt", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := Decrypt(sealed, []byte("correct horse")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("Decrypt of damaged content err = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestPendingEncryptAndReadDecrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db", "prod")
+	pending, err := Create(path)
+	if err != nil {
+		t.Fatalf("Create error = %v", err)
+	}
+	defer pending.Discard()
+	if _, err := pending.Write([]byte("hunter2")); err != nil {
+		t.Fatalf("Write error = %v", err)
+	}
+	if err := pending.Encrypt([]byte("pass")); err != nil {
+		t.Fatalf("Encrypt error = %v", err)
+	}
+	if err := pending.Commit(); err != nil {
+		t.Fatalf("Commit error = %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("dir holds %d files, want only the snippet", len(entries))
+	}
+
+	if _, err := ReadEncoded(path, EncodingEncrypted); !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("ReadEncoded err = %v, want ErrEncrypted", err)
+	}
+	asked := 0
+	passphrase := func() ([]byte, error) {
+		asked++
+		return []byte("pass"), nil
+	}
+	data, err := ReadDecrypted(path, EncodingEncrypted, passphrase)
+	if err != nil {
+		t.Fatalf("ReadDecrypted error = %v", err)
+	}
+	if string(data) != "hunter2" {
+		t.Fatalf("ReadDecrypted = %q, want hunter2", data)
+	}
+	if _, err := ReadDecrypted(path, EncodingNone, passphrase); err != nil {
+		t.Fatalf("ReadDecrypted of a plain file error = %v", err)
+	}
+	if asked != 1 {
+		t.Fatalf("passphrase asked for %d times, want only for the encrypted read", asked)
+	}
+}
+
+func TestPendingShredOnDiscard(t *testing.T) {
+	dir := t.TempDir()
+	pending, err := Create(filepath.Join(dir, "db", "prod"))
+	if err != nil {
+		t.Fatalf("Create error = %v", err)
+	}
+	pending.ShredOnDiscard()
+	if _, err := pending.Write([]byte("hunter2")); err != nil {
+		t.Fatalf("Write error = %v", err)
+	}
+	// A second link sees what becomes of the temp file's blocks.
+	link := filepath.Join(dir, "link")
+	if err := os.Link(pending.Name(), link); err != nil {
+		t.Fatalf("Link error = %v", err)
+	}
+	pending.Discard()
+
+	if _, err := os.Stat(pending.Name()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("temp file still there: %v", err)
+	}
+	data, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatalf("discarded temp file was not shredded: %q", data)
+	}
+}
+
+func TestShred(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "copy")
+	if err := os.WriteFile(path, []byte("hunter2"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	if err := Shred(path); err != nil {
+		t.Fatalf("Shred error = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat after Shred err = %v, want not exist", err)
+	}
+	if err := Shred(path); err != nil {
+		t.Fatalf("Shred of a missing file error = %v", err)
+	}
+}
@@ -5,6 +5,7 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -26,6 +27,34 @@ CREATE TABLE IF NOT EXISTS snippets (
 );
 `
 
+// migrations upgrade the schema one step at a time. A database's
+// PRAGMA user_version records how many have been applied, so steps
+// must only ever be appended.
+var migrations = []string{
+	schema,
+	`ALTER TABLE snippets ADD COLUMN fields TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE snippets ADD COLUMN language TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE snippets ADD COLUMN mime TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE snippets ADD COLUMN size INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE snippets ADD COLUMN encoding TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE snippets ADD COLUMN sha256 TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS snippets_sha256 ON snippets (sha256)`,
+	`ALTER TABLE snippets ADD COLUMN protected INTEGER NOT NULL DEFAULT 0`,
+}
+
+// backfills fill in columns added by the migration of the same number
+// from the snippet files, which live in dir beside the database.
+var backfills = map[int]func(tx *sql.Tx, dir string) error{
+	7: backfillSHA256,
+}
+
+// ErrSchemaOutdated is returned when a read-only database predates the
+// current schema and cannot be migrated in place.
+var ErrSchemaOutdated = errors.New("metadata schema is outdated")
+
+// ErrSchemaTooNew is returned for databases written by a newer wow.
+var ErrSchemaTooNew = errors.New("metadata schema is newer than this wow")
+
 // InitMetaDB initializes a SQLite database at the given path.
 // It ensures the directory exists, opens the database, applies migrations,
 // and returns the database handle.
@@ -45,7 +74,7 @@ func InitMetaDB(path string) (*sql.DB, error) {
 	db.SetConnMaxIdleTime(0)
 	db.SetConnMaxLifetime(0)
 
-	if err := migrate(db); err != nil {
+	if err := migrate(db, filepath.Dir(path)); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
@@ -53,6 +82,40 @@ func InitMetaDB(path string) (*sql.DB, error) {
 	return db, nil
 }
 
+// OpenMetaDBReadOnly opens an existing SQLite database at the given path
+// without creating, migrating or writing to it.
+// It is used for vaults wow only reads from, such as shared checkouts.
+func OpenMetaDBReadOnly(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("open sqlite db %q: %w", path, err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=ro&_busy_timeout=%d", url.PathEscape(path), int((5 * time.Second).Milliseconds()))
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite db %q: %w", path, err)
+	}
+
+	db.SetMaxOpenConns(1)
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("open sqlite db %q: %w", path, err)
+	}
+	if version < len(migrations) {
+		_ = db.Close()
+		return nil, fmt.Errorf("open sqlite db %q: %w; run wow against it once with write access", path, ErrSchemaOutdated)
+	}
+	if version > len(migrations) {
+		_ = db.Close()
+		return nil, fmt.Errorf("open sqlite db %q: %w", path, ErrSchemaTooNew)
+	}
+
+	return db, nil
+}
+
 // ensureDir creates the parent directory for the given path if it does not exist.
 func ensureDir(path string) error {
 	dir := filepath.Dir(path)
@@ -67,10 +130,85 @@ func buildDSN(path string) string {
 	return fmt.Sprintf("file:%s?_busy_timeout=%d&_journal_mode=WAL&_foreign_keys=ON", url.PathEscape(path), int((5 * time.Second).Milliseconds()))
 }
 
-// migrate applies the schema to the database if it is not already present.
-func migrate(db *sql.DB) error {
-	if _, err := db.Exec(schema); err != nil {
-		return fmt.Errorf("apply schema: %w", err)
+// migrate applies any migrations the database has not seen yet.
+// dir holds the snippet files, for migrations that read them.
+func migrate(db *sql.DB, dir string) error {
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return ErrSchemaTooNew
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("apply migration %d: %w", i+1, err)
+		}
+		if fill, ok := backfills[i+1]; ok {
+			if err := fill(tx, dir); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("apply migration %d: %w", i+1, err)
+			}
+		}
+		// PRAGMA does not take bind parameters.
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("apply migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("apply migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// backfillSHA256 hashes the content of every snippet saved before
+// hashes were recorded. Snippets whose file is missing are left blank.
+func backfillSHA256(tx *sql.Tx, dir string) error {
+	rows, err := tx.Query(`SELECT key, encoding FROM snippets WHERE sha256 = ''`)
+	if err != nil {
+		return fmt.Errorf("list snippets to hash: %w", err)
+	}
+	type snippet struct{ key, encoding string }
+	var snippets []snippet
+	for rows.Next() {
+		var s snippet
+		if err := rows.Scan(&s.key, &s.encoding); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("scan snippet to hash: %w", err)
+		}
+		snippets = append(snippets, s)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list snippets to hash: %w", err)
+	}
+
+	for _, s := range snippets {
+		sum, err := HashFile(filepath.Join(dir, filepath.FromSlash(s.key)), s.encoding)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("hash %q: %w", s.key, err)
+		}
+		if _, err := tx.Exec(`UPDATE snippets SET sha256 = ? WHERE key = ?`, sum, s.key); err != nil {
+			return fmt.Errorf("store hash of %q: %w", s.key, err)
+		}
 	}
 	return nil
 }
+
+func schemaVersion(db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
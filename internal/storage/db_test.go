@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -30,3 +34,140 @@ func TestInitMetaDBCreatesSchema(t *testing.T) {
 		t.Fatalf("expected db file to exist: %v", err)
 	}
 }
+
+func TestOpenMetaDBReadOnlyRejectsWrites(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "meta.db")
+
+	rw, err := InitMetaDB(dbPath)
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	_ = rw.Close()
+
+	db, err := OpenMetaDBReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("OpenMetaDBReadOnly error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	if _, err := db.Exec("DELETE FROM snippets"); err == nil {
+		t.Fatalf("expected write to read-only db to fail")
+	}
+}
+
+func TestOpenMetaDBReadOnlyMissing(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "meta.db")
+	if _, err := OpenMetaDBReadOnly(dbPath); err == nil {
+		t.Fatalf("expected error for missing db")
+	}
+	if _, err := os.Stat(dbPath); err == nil {
+		t.Fatalf("read-only open should not create %q", dbPath)
+	}
+}
+
+func TestInitMetaDBMigratesUnversionedDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.db")
+
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open error = %v", err)
+	}
+	if _, err := old.Exec(schema); err != nil {
+		t.Fatalf("create v0 schema error = %v", err)
+	}
+	if _, err := old.Exec(`INSERT INTO snippets (key, type, created, modified, description, tags) VALUES ('a', 'text', '2024-01-01', '2024-01-01', '', '')`); err != nil {
+		t.Fatalf("insert error = %v", err)
+	}
+	_ = old.Close()
+
+	db, err := InitMetaDB(path)
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	defer db.Close()
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		t.Fatalf("schemaVersion error = %v", err)
+	}
+	if version != len(migrations) {
+		t.Fatalf("version = %d, want %d", version, len(migrations))
+	}
+
+	meta, err := GetMetadata(context.Background(), db, "a")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if meta.Fields != nil {
+		t.Fatalf("Fields = %v, want none", meta.Fields)
+	}
+}
+
+func TestOpenMetaDBReadOnlyRejectsOutdatedSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.db")
+
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open error = %v", err)
+	}
+	if _, err := old.Exec(schema); err != nil {
+		t.Fatalf("create v0 schema error = %v", err)
+	}
+	_ = old.Close()
+
+	if _, err := OpenMetaDBReadOnly(path); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("OpenMetaDBReadOnly error = %v, want ErrSchemaOutdated", err)
+	}
+}
+
+func TestInitMetaDBBackfillsSHA256(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meta.db")
+
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open error = %v", err)
+	}
+	if _, err := old.Exec(schema); err != nil {
+		t.Fatalf("create v0 schema error = %v", err)
+	}
+	for _, k := range []string{"notes/a", "gone"} {
+		if _, err := old.Exec(`INSERT INTO snippets (key, type, created, modified, description, tags) VALUES (?, 'text', '2024-01-01', '2024-01-01', '', '')`, k); err != nil {
+			t.Fatalf("insert error = %v", err)
+		}
+	}
+	_ = old.Close()
+	if err := Save(filepath.Join(dir, "notes", "a"), strings.NewReader("hello\n")); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+
+	db, err := InitMetaDB(path)
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	meta, err := GetMetadata(ctx, db, "notes/a")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	// sha256 of "hello\n".
+	const want = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	if meta.SHA256 != want {
+		t.Fatalf("SHA256 = %q, want %q", meta.SHA256, want)
+	}
+	if gone, _ := GetMetadata(ctx, db, "gone"); gone.SHA256 != "" {
+		t.Fatalf("SHA256 of a missing file = %q, want blank", gone.SHA256)
+	}
+
+	keys, err := KeysWithSHA256(ctx, db, want)
+	if err != nil {
+		t.Fatalf("KeysWithSHA256 error = %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "notes/a" {
+		t.Fatalf("KeysWithSHA256 = %v, want [notes/a]", keys)
+	}
+}
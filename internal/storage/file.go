@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -11,48 +14,197 @@ import (
 // ErrNotFound is returned when the snippet file is missing.
 var ErrNotFound = os.ErrNotExist
 
+// ErrUnknownEncoding is returned for files stored in an encoding this
+// version of wow cannot read.
+var ErrUnknownEncoding = errors.New("unknown snippet encoding")
+
+// Encodings a snippet file may be stored in. The metadata records
+// which one applies, as content alone cannot tell a compressed
+// snippet from a snippet of compressed data.
+const (
+	EncodingNone      = ""
+	EncodingGzip      = "gzip"
+	EncodingEncrypted = "aes-gcm" // sealed by Encrypt; read with ReadDecrypted.
+)
+
 // Save writes content to the given path using an atomic workflow.
 // It ensures parent directories exist and applies 0600 permissions.
 func Save(path string, content io.Reader) error {
+	pending, err := Create(path)
+	if err != nil {
+		return err
+	}
+	defer pending.Discard()
+
+	if _, err := io.Copy(pending, content); err != nil {
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	return pending.Commit()
+}
+
+// Pending is a snippet file being written to a temp file beside its
+// path, so a failed write never leaves half a snippet behind.
+type Pending struct {
+	file  *os.File
+	path  string
+	done  bool
+	shred bool
+}
+
+// Create starts writing the snippet file at path. Commit moves it into
+// place; Discard, which is safe to defer, throws it away.
+func Create(path string) (*Pending, error) {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o700); err != nil {
-		return fmt.Errorf("create snippet dir %q: %w", dir, err)
+		return nil, fmt.Errorf("create snippet dir %q: %w", dir, err)
 	}
 
+	tmp, err := createTemp(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Pending{file: tmp, path: path}, nil
+}
+
+func createTemp(dir string) (*os.File, error) {
 	tmp, err := os.CreateTemp(dir, ".wow-*")
 	if err != nil {
-		return fmt.Errorf("create temp file: %w", err)
+		return nil, fmt.Errorf("create temp file: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return nil, fmt.Errorf("chmod temp file: %w", err)
+	}
+	return tmp, nil
+}
+
+// Write appends b to the temp file.
+func (p *Pending) Write(b []byte) (int, error) {
+	return p.file.Write(b)
+}
+
+// Name returns the path of the temp file, for hooks to read what has
+// been written before it is committed.
+func (p *Pending) Name() string {
+	return p.file.Name()
+}
+
+// Size returns how many bytes the file holds so far.
+func (p *Pending) Size() (int64, error) {
+	info, err := p.file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat temp file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// SHA256 returns the hex SHA-256 of what has been written so far, as
+// stored. Nothing more may be written afterwards.
+func (p *Pending) SHA256() (string, error) {
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("seek temp file: %w", err)
+	}
+	sum := sha256.New()
+	if _, err := io.Copy(sum, p.file); err != nil {
+		return "", fmt.Errorf("hash temp file: %w", err)
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// Compress gzips what has been written so far. The file is only
+// replaced when that makes it smaller, and the returned encoding says
+// which way it went. Nothing more may be written afterwards.
+func (p *Pending) Compress() (string, error) {
+	if p.done {
+		return EncodingNone, errors.New("snippet file already committed or discarded")
+	}
+	raw, err := p.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return EncodingNone, fmt.Errorf("seek temp file: %w", err)
+	}
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+		return EncodingNone, fmt.Errorf("seek temp file: %w", err)
 	}
-	tmpPath := tmp.Name()
 
-	cleanup := func() {
+	tmp, err := createTemp(filepath.Dir(p.path))
+	if err != nil {
+		return EncodingNone, err
+	}
+	discard := func() {
 		_ = tmp.Close()
-		_ = os.Remove(tmpPath)
+		_ = os.Remove(tmp.Name())
 	}
 
-	if err := tmp.Chmod(0o600); err != nil {
-		cleanup()
-		return fmt.Errorf("chmod temp file: %w", err)
+	zw, err := gzip.NewWriterLevel(tmp, gzip.BestCompression)
+	if err != nil {
+		discard()
+		return EncodingNone, fmt.Errorf("compress snippet: %w", err)
+	}
+	if _, err := io.Copy(zw, p.file); err != nil {
+		discard()
+		return EncodingNone, fmt.Errorf("compress snippet: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		discard()
+		return EncodingNone, fmt.Errorf("compress snippet: %w", err)
+	}
+	compressed, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		discard()
+		return EncodingNone, fmt.Errorf("seek temp file: %w", err)
 	}
 
-	if _, err := io.Copy(tmp, content); err != nil {
-		cleanup()
-		return fmt.Errorf("write temp file: %w", err)
+	if compressed >= raw {
+		discard()
+		return EncodingNone, nil
 	}
+	_ = p.file.Close()
+	_ = os.Remove(p.file.Name())
+	p.file = tmp
+	return EncodingGzip, nil
+}
 
-	if err := tmp.Close(); err != nil {
+// Commit moves the written file to its path.
+func (p *Pending) Commit() error {
+	if p.done {
+		return errors.New("snippet file already committed or discarded")
+	}
+	p.done = true
+	tmpPath := p.file.Name()
+
+	if err := p.file.Close(); err != nil {
 		_ = os.Remove(tmpPath)
 		return fmt.Errorf("close temp file: %w", err)
 	}
 
-	if err := os.Rename(tmpPath, path); err != nil {
+	if err := os.Rename(tmpPath, p.path); err != nil {
 		_ = os.Remove(tmpPath)
 		return fmt.Errorf("rename temp file: %w", err)
 	}
-
 	return nil
 }
 
+// ShredOnDiscard makes Discard shred the temp file rather than just
+// remove it, for plaintext that was to be encrypted.
+func (p *Pending) ShredOnDiscard() {
+	p.shred = true
+}
+
+// Discard removes the temp file, unless it was committed.
+func (p *Pending) Discard() {
+	if p.done {
+		return
+	}
+	p.done = true
+	_ = p.file.Close()
+	if p.shred {
+		_ = Shred(p.file.Name())
+		return
+	}
+	_ = os.Remove(p.file.Name())
+}
+
 // Read returns the contents of the snippet file at the given path.
 func Read(path string) ([]byte, error) {
 	data, err := os.ReadFile(path)
@@ -65,6 +217,95 @@ func Read(path string) ([]byte, error) {
 	return data, nil
 }
 
+// ReadEncoded returns the contents of the snippet file at path,
+// decoding them from the encoding it is stored in.
+func ReadEncoded(path, encoding string) ([]byte, error) {
+	if encoding == EncodingNone {
+		return Read(path)
+	}
+	r, err := Open(path, encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read snippet file: %w", err)
+	}
+	return data, nil
+}
+
+// Open opens the snippet file at path for reading, decoding it from
+// the encoding it is stored in. Encrypted files are refused with
+// ErrEncrypted.
+func Open(path, encoding string) (io.ReadCloser, error) {
+	if encoding == EncodingEncrypted {
+		return nil, ErrEncrypted
+	}
+	if encoding != EncodingNone && encoding != EncodingGzip {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEncoding, encoding)
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open snippet file: %w", err)
+	}
+	if encoding == EncodingNone {
+		return f, nil
+	}
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("decompress snippet file: %w", err)
+	}
+	return gzipFile{Reader: zr, file: f}, nil
+}
+
+// HashFile returns the hex SHA-256 of the decoded content of the
+// snippet file at path. Encrypted files are hashed as stored, so the
+// hash says nothing about their plaintext.
+func HashFile(path, encoding string) (string, error) {
+	r, err := Open(path, HashedEncoding(encoding))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, r); err != nil {
+		return "", fmt.Errorf("hash snippet file: %w", err)
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// HashedEncoding returns the encoding a file is read in to hash it:
+// its own, except for encrypted files, which are hashed as stored.
+func HashedEncoding(encoding string) string {
+	if encoding == EncodingEncrypted {
+		return EncodingNone
+	}
+	return encoding
+}
+
+// gzipFile decompresses a snippet file, closing both when done.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipFile) Close() error {
+	err := g.Reader.Close()
+	if closeErr := g.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
 // Delete removes the snippet file at the given path.
 func Delete(path string) error {
 	err := os.Remove(path)
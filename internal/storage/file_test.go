@@ -2,6 +2,7 @@ package storage
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -57,3 +58,118 @@ func TestExists(t *testing.T) {
 		t.Fatalf("Exists should be true after writing")
 	}
 }
+
+func TestCreateDiscardLeavesNothingBehind(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snippets", "foo")
+
+	pending, err := Create(path)
+	if err != nil {
+		t.Fatalf("Create error = %v", err)
+	}
+	if _, err := pending.Write([]byte("partial")); err != nil {
+		t.Fatalf("Write error = %v", err)
+	}
+	pending.Discard()
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("entries = %v, want none", entries)
+	}
+	if err := pending.Commit(); err == nil {
+		t.Fatalf("expected error committing a discarded file")
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "big")
+	want := bytes.Repeat([]byte("GET /healthz 200\n"), 1000)
+
+	pending, err := Create(path)
+	if err != nil {
+		t.Fatalf("Create error = %v", err)
+	}
+	defer pending.Discard()
+	if _, err := pending.Write(want); err != nil {
+		t.Fatalf("Write error = %v", err)
+	}
+	encoding, err := pending.Compress()
+	if err != nil {
+		t.Fatalf("Compress error = %v", err)
+	}
+	if encoding != EncodingGzip {
+		t.Fatalf("encoding = %q, want gzip", encoding)
+	}
+	if err := pending.Commit(); err != nil {
+		t.Fatalf("Commit error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat error = %v", err)
+	}
+	if info.Size() >= int64(len(want)) {
+		t.Fatalf("stored %d bytes, want fewer than %d", info.Size(), len(want))
+	}
+	got, err := ReadEncoded(path, encoding)
+	if err != nil {
+		t.Fatalf("ReadEncoded error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("ReadEncoded returned %d bytes, want %d", len(got), len(want))
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("entries = %v, want only the snippet", entries)
+	}
+}
+
+func TestCompressKeepsIncompressibleContent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tiny")
+
+	pending, err := Create(path)
+	if err != nil {
+		t.Fatalf("Create error = %v", err)
+	}
+	defer pending.Discard()
+	if _, err := pending.Write([]byte("hi")); err != nil {
+		t.Fatalf("Write error = %v", err)
+	}
+	encoding, err := pending.Compress()
+	if err != nil {
+		t.Fatalf("Compress error = %v", err)
+	}
+	if encoding != EncodingNone {
+		t.Fatalf("encoding = %q, want none", encoding)
+	}
+	if err := pending.Commit(); err != nil {
+		t.Fatalf("Commit error = %v", err)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read error = %v", err)
+	}
+	if string(got) != "hi" {
+		t.Fatalf("Read = %q, want hi", got)
+	}
+}
+
+func TestOpenRejectsUnknownEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo")
+	if err := Save(path, bytes.NewReader([]byte("x"))); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+	if _, err := ReadEncoded(path, "brotli"); !errors.Is(err, ErrUnknownEncoding) {
+		t.Fatalf("ReadEncoded error = %v, want ErrUnknownEncoding", err)
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -21,10 +22,14 @@ var ErrMetadataDuplicate = errors.New("metadata already exists")
 // InsertMetadata inserts a new metadata row for the provided snippet key.
 func InsertMetadata(ctx context.Context, db *sql.DB, meta model.Metadata) error {
 	const query = `
-INSERT INTO snippets (key, type, created, modified, description, tags)
-VALUES (?, ?, ?, ?, ?, ?)
+INSERT INTO snippets (key, type, language, mime, size, encoding, sha256, created, modified, description, tags, fields, protected)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
-	_, err := db.ExecContext(ctx, query, meta.Key, meta.Type, meta.Created.UTC(), meta.Modified.UTC(), meta.Description, meta.Tags)
+	fields, err := encodeFields(meta.Fields)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, query, meta.Key, meta.Type, meta.Language, meta.MIME, meta.Size, meta.Encoding, meta.SHA256, meta.Created.UTC(), meta.Modified.UTC(), meta.Description, meta.Tags, fields, meta.Protected)
 	if err != nil {
 		if sqliteIsUniqueError(err) {
 			return ErrMetadataDuplicate
@@ -37,18 +42,26 @@ VALUES (?, ?, ?, ?, ?, ?)
 // GetMetadata retrieves metadata for the provided snippet key.
 func GetMetadata(ctx context.Context, db *sql.DB, key string) (model.Metadata, error) {
 	const query = `
-SELECT key, type, created, modified, description, tags
+SELECT key, type, language, mime, size, encoding, sha256, created, modified, description, tags, fields, protected
 FROM snippets
 WHERE key = ?
 `
 	var meta model.Metadata
+	var fields string
 	err := db.QueryRowContext(ctx, query, key).Scan(
 		&meta.Key,
 		&meta.Type,
+		&meta.Language,
+		&meta.MIME,
+		&meta.Size,
+		&meta.Encoding,
+		&meta.SHA256,
 		&meta.Created,
 		&meta.Modified,
 		&meta.Description,
 		&meta.Tags,
+		&fields,
+		&meta.Protected,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return model.Metadata{}, ErrMetadataNotFound
@@ -56,13 +69,16 @@ WHERE key = ?
 	if err != nil {
 		return model.Metadata{}, fmt.Errorf("get metadata: %w", err)
 	}
+	if meta.Fields, err = decodeFields(fields); err != nil {
+		return model.Metadata{}, err
+	}
 	return meta, nil
 }
 
 // ListMetadata retrieves all metadata rows ordered from newest to oldest.
 func ListMetadata(ctx context.Context, db *sql.DB) ([]model.Metadata, error) {
 	const query = `
-SELECT key, type, created, modified, description, tags
+SELECT key, type, language, mime, size, encoding, sha256, created, modified, description, tags, fields, protected
 FROM snippets
 ORDER BY created DESC
 `
@@ -75,16 +91,27 @@ ORDER BY created DESC
 	var result []model.Metadata
 	for rows.Next() {
 		var meta model.Metadata
+		var fields string
 		if err := rows.Scan(
 			&meta.Key,
 			&meta.Type,
+			&meta.Language,
+			&meta.MIME,
+			&meta.Size,
+			&meta.Encoding,
+			&meta.SHA256,
 			&meta.Created,
 			&meta.Modified,
 			&meta.Description,
 			&meta.Tags,
+			&fields,
+			&meta.Protected,
 		); err != nil {
 			return nil, fmt.Errorf("scan metadata row: %w", err)
 		}
+		if meta.Fields, err = decodeFields(fields); err != nil {
+			return nil, err
+		}
 		result = append(result, meta)
 	}
 
@@ -95,6 +122,35 @@ ORDER BY created DESC
 	return result, nil
 }
 
+// KeysWithSHA256 returns the keys of snippets whose content hashes to
+// sum, in order.
+func KeysWithSHA256(ctx context.Context, db *sql.DB, sum string) ([]string, error) {
+	const query = `
+SELECT key
+FROM snippets
+WHERE sha256 = ?
+ORDER BY key
+`
+	rows, err := db.QueryContext(ctx, query, sum)
+	if err != nil {
+		return nil, fmt.Errorf("find snippets by hash: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("scan snippet key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("find snippets by hash: %w", err)
+	}
+	return keys, nil
+}
+
 // DeleteMetadata removes the metadata row for the provided key.
 func DeleteMetadata(ctx context.Context, db *sql.DB, key string) error {
 	const query = `
@@ -125,10 +181,14 @@ func UpdateMetadata(ctx context.Context, db *sql.DB, meta model.Metadata) error
 	}
 	const query = `
 UPDATE snippets
-SET type = ?, modified = ?, description = ?, tags = ?
+SET type = ?, language = ?, mime = ?, size = ?, encoding = ?, sha256 = ?, modified = ?, description = ?, tags = ?, fields = ?, protected = ?
 WHERE key = ?
 `
-	res, err := db.ExecContext(ctx, query, meta.Type, meta.Modified.UTC(), meta.Description, meta.Tags, meta.Key)
+	fields, err := encodeFields(meta.Fields)
+	if err != nil {
+		return err
+	}
+	res, err := db.ExecContext(ctx, query, meta.Type, meta.Language, meta.MIME, meta.Size, meta.Encoding, meta.SHA256, meta.Modified.UTC(), meta.Description, meta.Tags, fields, meta.Protected, meta.Key)
 	if err != nil {
 		return fmt.Errorf("update metadata: %w", err)
 	}
@@ -142,6 +202,29 @@ WHERE key = ?
 	return nil
 }
 
+// encodeFields stores custom fields as a JSON object, or "" when there are none.
+func encodeFields(fields map[string]string) (string, error) {
+	if len(fields) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("encode fields: %w", err)
+	}
+	return string(data), nil
+}
+
+func decodeFields(raw string) (map[string]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return nil, fmt.Errorf("decode fields: %w", err)
+	}
+	return fields, nil
+}
+
 func sqliteIsUniqueError(err error) bool {
 	var se sqlite3.Error
 	if !errors.As(err, &se) {
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -24,7 +25,8 @@ func TestInsertAndGetMetadata(t *testing.T) {
 	now := time.Unix(1_700_000_000, 0)
 	meta := model.Metadata{
 		Key:         "go/foo",
-		Type:        "text",
+		Type:        "code",
+		Language:    "go",
 		Created:     now,
 		Modified:    now,
 		Description: "desc",
@@ -42,7 +44,7 @@ func TestInsertAndGetMetadata(t *testing.T) {
 		t.Fatalf("GetMetadata error = %v", err)
 	}
 
-	if got != meta {
+	if !reflect.DeepEqual(got, meta) {
 		t.Fatalf("GetMetadata = %+v, want %+v", got, meta)
 	}
 }
@@ -229,3 +231,31 @@ func TestUpdateMetadata(t *testing.T) {
 		t.Fatalf("Created changed = %v, want %v", got.Created, created)
 	}
 }
+
+func TestUpdateMetadataStoresFields(t *testing.T) {
+	ctx := context.Background()
+	db, err := InitMetaDB(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	defer db.Close()
+
+	now := time.Unix(1_700_000_000, 0).UTC()
+	meta := model.Metadata{Key: "a", Type: "text", Created: now, Modified: now}
+	if err := InsertMetadata(ctx, db, meta); err != nil {
+		t.Fatalf("InsertMetadata error = %v", err)
+	}
+
+	meta.Fields = map[string]string{"owner": "sam"}
+	if err := UpdateMetadata(ctx, db, meta); err != nil {
+		t.Fatalf("UpdateMetadata error = %v", err)
+	}
+
+	got, err := GetMetadata(ctx, db, "a")
+	if err != nil {
+		t.Fatalf("GetMetadata error = %v", err)
+	}
+	if got.Fields["owner"] != "sam" {
+		t.Fatalf("Fields = %v, want owner=sam", got.Fields)
+	}
+}
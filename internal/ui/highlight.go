@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"bytes"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Highlight returns source coloured for the terminal.
+// The lexer comes from lang when it names one, then from the extension
+// of filename, then from the content itself. It returns source as is
+// when no lexer fits or the terminal has no colours.
+func Highlight(source []byte, filename, lang string) []byte {
+	lexer := pickLexer(source, filename, lang)
+	if lexer == nil {
+		return source
+	}
+
+	formatter := terminalFormatter()
+	if formatter == nil {
+		return source
+	}
+
+	// Match the palette to the background, as DefaultStyles does.
+	style := styles.Get("github")
+	if lipgloss.HasDarkBackground() {
+		style = styles.Get("monokai")
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, string(source))
+	if err != nil {
+		return source
+	}
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, style, iterator); err != nil {
+		return source
+	}
+	return buf.Bytes()
+}
+
+func pickLexer(source []byte, filename, lang string) chroma.Lexer {
+	if lang != "" {
+		if lexer := lexers.Get(lang); lexer != nil {
+			return lexer
+		}
+	}
+	if filename != "" {
+		if lexer := lexers.Match(filename); lexer != nil {
+			return lexer
+		}
+	}
+	return lexers.Analyse(string(source))
+}
+
+func terminalFormatter() chroma.Formatter {
+	switch lipgloss.ColorProfile() {
+	case termenv.TrueColor:
+		return formatters.Get("terminal16m")
+	case termenv.ANSI256:
+		return formatters.Get("terminal256")
+	case termenv.ANSI:
+		return formatters.Get("terminal16")
+	default:
+		return nil
+	}
+}
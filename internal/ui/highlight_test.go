@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestHighlightColoursCode(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.ANSI256)
+
+	source := []byte("package main\n\nfunc main() {}\n")
+	got := Highlight(source, "snips/main.go", "")
+	if bytes.Equal(got, source) || !bytes.Contains(got, []byte("\x1b[")) {
+		t.Fatalf("Highlight = %q, want ANSI colours", got)
+	}
+
+	// The stored language wins over the extension.
+	if sql := Highlight([]byte("SELECT 1;\n"), "notes/query.txt", "sql"); !bytes.Contains(sql, []byte("\x1b[")) {
+		t.Fatalf("Highlight with lang = %q, want ANSI colours", sql)
+	}
+}
+
+func TestHighlightKeepsPlainTextWithoutColours(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.Ascii)
+
+	source := []byte("package main\n")
+	if got := Highlight(source, "snips/main.go", ""); !bytes.Equal(got, source) {
+		t.Fatalf("Highlight = %q, want source unchanged", got)
+	}
+}
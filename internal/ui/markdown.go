@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// RenderMarkdown renders source for a terminal width columns wide.
+// Headings, lists, code fences and links are styled for the background,
+// as DefaultStyles does; without colours only the layout remains.
+func RenderMarkdown(source []byte, width int) ([]byte, error) {
+	profile := lipgloss.ColorProfile()
+
+	style := styles.LightStyle
+	switch {
+	case profile == termenv.Ascii:
+		style = styles.NoTTYStyle
+	case lipgloss.HasDarkBackground():
+		style = styles.DarkStyle
+	}
+
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(style),
+		glamour.WithColorProfile(profile),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return renderer.RenderBytes(source)
+}
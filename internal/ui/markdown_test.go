@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestRenderMarkdownWrapsWithoutColours(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.Ascii)
+
+	source := "# Title\n\n- one\n- two\n\n" + strings.Repeat("word ", 30) + "\n\n```go\nfunc main() {}\n```\n"
+	got, err := RenderMarkdown([]byte(source), 40)
+	if err != nil {
+		t.Fatalf("RenderMarkdown error = %v", err)
+	}
+
+	out := string(got)
+	if strings.Contains(out, "\x1b[") {
+		t.Fatalf("RenderMarkdown = %q, want no colours", out)
+	}
+	for _, want := range []string{"Title", "• one", "func main() {}"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("RenderMarkdown = %q, want %q", out, want)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 40 {
+			t.Fatalf("line %q is wider than 40 columns", line)
+		}
+	}
+}
+
+func TestRenderMarkdownColoursOnTerminal(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.ANSI256)
+
+	got, err := RenderMarkdown([]byte("# Title\n"), 80)
+	if err != nil {
+		t.Fatalf("RenderMarkdown error = %v", err)
+	}
+	if !strings.Contains(string(got), "\x1b[") {
+		t.Fatalf("RenderMarkdown = %q, want ANSI colours", got)
+	}
+}
@@ -0,0 +1,239 @@
+// vault layers several snippet stores into one search path.
+// Each vault is a directory of snippet files with its own metadata DB.
+// Reads walk the vaults in order and the first match wins;
+// writes only ever go to the primary vault, the one writable vault.
+package vault
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/llywelwyn/wow/internal/config"
+	"github.com/llywelwyn/wow/internal/key"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// ErrReadOnly is returned when a write targets a snippet in a read-only vault.
+var ErrReadOnly = errors.New("snippet is in a read-only vault")
+
+// Vault is an opened snippet store.
+type Vault struct {
+	Name     string
+	BaseDir  string
+	DB       *sql.DB
+	ReadOnly bool
+}
+
+// Stack is an ordered search path of vaults.
+type Stack []Vault
+
+// Shadow records a key present in more than one vault.
+type Shadow struct {
+	Key    string
+	Winner string   // vault the key resolves to.
+	Hidden []string // vaults whose copy is unreachable, in precedence order.
+}
+
+// Open opens every configured vault. Writable vaults are created and
+// migrated as needed, even when the scope marks them read-only, so a
+// vault is never left behind the schema by where wow ran. Shared vaults
+// without a metadata DB are opened without one, so their files still
+// resolve but nothing is listed.
+func Open(vaults []config.Vault) (Stack, error) {
+	stack := make(Stack, 0, len(vaults))
+	for _, v := range vaults {
+		db, err := openDB(v)
+		if err != nil {
+			_ = stack.Close()
+			return nil, fmt.Errorf("vault %q: %w", v.Name, err)
+		}
+		stack = append(stack, Vault{
+			Name:     v.Name,
+			BaseDir:  v.BaseDir,
+			DB:       db,
+			ReadOnly: v.ReadOnly,
+		})
+	}
+	return stack, nil
+}
+
+func openDB(v config.Vault) (*sql.DB, error) {
+	if v.Writable() {
+		return storage.InitMetaDB(v.MetaDB)
+	}
+	if _, err := os.Stat(v.MetaDB); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return storage.OpenMetaDBReadOnly(v.MetaDB)
+}
+
+// Close closes every vault's metadata DB.
+func (s Stack) Close() error {
+	var errs []error
+	for _, v := range s {
+		if v.DB == nil {
+			continue
+		}
+		if err := v.DB.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Primary returns the vault that receives writes.
+func (s Stack) Primary() Vault {
+	for _, v := range s {
+		if !v.ReadOnly {
+			return v
+		}
+	}
+	return Vault{}
+}
+
+// Resolve returns the first vault holding a snippet file for key, and its path.
+// It returns storage.ErrNotFound when no vault has the file.
+func (s Stack) Resolve(rawKey string) (Vault, string, error) {
+	normalized, err := key.Normalize(rawKey)
+	if err != nil {
+		return Vault{}, "", err
+	}
+
+	for _, v := range s {
+		path, err := key.ResolvePath(v.BaseDir, normalized)
+		if err != nil {
+			return Vault{}, "", err
+		}
+		exists, err := storage.Exists(path)
+		if err != nil {
+			return Vault{}, "", err
+		}
+		if exists {
+			return v, path, nil
+		}
+	}
+	return Vault{}, "", storage.ErrNotFound
+}
+
+// Read returns the content of the first snippet file for key, decoded
+// from the encoding its vault's metadata records. Encrypted snippets
+// are decrypted with passphrase; with none they fail with
+// storage.ErrEncrypted.
+func (s Stack) Read(ctx context.Context, rawKey string, passphrase storage.Passphrase) ([]byte, error) {
+	found, path, err := s.Resolve(rawKey)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := storage.EncodingNone
+	if found.DB != nil {
+		normalized, err := key.Normalize(rawKey)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := storage.GetMetadata(ctx, found.DB, normalized)
+		if err != nil && !errors.Is(err, storage.ErrMetadataNotFound) {
+			return nil, fmt.Errorf("vault %q: %w", found.Name, err)
+		}
+		encoding = meta.Encoding
+	}
+	return storage.ReadDecrypted(path, encoding, passphrase)
+}
+
+// Lookup returns the first vault holding metadata for key, with the metadata
+// tagged by vault name. It returns storage.ErrMetadataNotFound when absent.
+func (s Stack) Lookup(ctx context.Context, rawKey string) (Vault, model.Metadata, error) {
+	normalized, err := key.Normalize(rawKey)
+	if err != nil {
+		return Vault{}, model.Metadata{}, err
+	}
+
+	for _, v := range s {
+		if v.DB == nil {
+			continue
+		}
+		meta, err := storage.GetMetadata(ctx, v.DB, normalized)
+		if errors.Is(err, storage.ErrMetadataNotFound) {
+			continue
+		}
+		if err != nil {
+			return Vault{}, model.Metadata{}, fmt.Errorf("vault %q: %w", v.Name, err)
+		}
+		meta.Vault = v.Name
+		return v, meta, nil
+	}
+	return Vault{}, model.Metadata{}, storage.ErrMetadataNotFound
+}
+
+// List merges metadata from every vault, newest first.
+// Keys shadowed by a higher-precedence vault are left out.
+func (s Stack) List(ctx context.Context) ([]model.Metadata, error) {
+	var result []model.Metadata
+	seen := make(map[string]bool)
+	for _, v := range s {
+		entries, err := listVault(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		for _, meta := range entries {
+			if seen[meta.Key] {
+				continue
+			}
+			seen[meta.Key] = true
+			result = append(result, meta)
+		}
+	}
+	// Stable, so a single vault keeps the order storage returned.
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Created.After(result[j].Created)
+	})
+	return result, nil
+}
+
+// Shadowed reports every key that exists in more than one vault.
+func (s Stack) Shadowed(ctx context.Context) ([]Shadow, error) {
+	owners := make(map[string][]string)
+	var order []string
+	for _, v := range s {
+		entries, err := listVault(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		for _, meta := range entries {
+			if _, ok := owners[meta.Key]; !ok {
+				order = append(order, meta.Key)
+			}
+			owners[meta.Key] = append(owners[meta.Key], v.Name)
+		}
+	}
+
+	var shadows []Shadow
+	for _, k := range order {
+		names := owners[k]
+		if len(names) < 2 {
+			continue
+		}
+		shadows = append(shadows, Shadow{Key: k, Winner: names[0], Hidden: names[1:]})
+	}
+	sort.Slice(shadows, func(i, j int) bool { return shadows[i].Key < shadows[j].Key })
+	return shadows, nil
+}
+
+func listVault(ctx context.Context, v Vault) ([]model.Metadata, error) {
+	if v.DB == nil {
+		return nil, nil
+	}
+	entries, err := storage.ListMetadata(ctx, v.DB)
+	if err != nil {
+		return nil, fmt.Errorf("vault %q: %w", v.Name, err)
+	}
+	for i := range entries {
+		entries[i].Vault = v.Name
+	}
+	return entries, nil
+}
@@ -0,0 +1,160 @@
+package vault
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llywelwyn/wow/internal/config"
+	"github.com/llywelwyn/wow/internal/model"
+	"github.com/llywelwyn/wow/internal/storage"
+)
+
+// seedVault creates a vault directory holding the given snippets.
+func seedVault(t *testing.T, snippets map[string]time.Time) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	db, err := storage.InitMetaDB(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("InitMetaDB error = %v", err)
+	}
+	defer db.Close()
+
+	for k, created := range snippets {
+		if err := storage.Save(filepath.Join(dir, filepath.FromSlash(k)), strings.NewReader(dir+":"+k)); err != nil {
+			t.Fatalf("Save error = %v", err)
+		}
+		meta := model.Metadata{Key: k, Type: "text", Created: created, Modified: created}
+		if err := storage.InsertMetadata(context.Background(), db, meta); err != nil {
+			t.Fatalf("InsertMetadata error = %v", err)
+		}
+	}
+	return dir
+}
+
+func openStack(t *testing.T, user, team string) Stack {
+	t.Helper()
+
+	stack, err := Open([]config.Vault{
+		{Name: "user", BaseDir: user, MetaDB: filepath.Join(user, "meta.db")},
+		{Name: "team", BaseDir: team, MetaDB: filepath.Join(team, "meta.db"), ReadOnly: true},
+	})
+	if err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	t.Cleanup(func() { _ = stack.Close() })
+	return stack
+}
+
+func TestStackResolveFirstMatchWins(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	user := seedVault(t, map[string]time.Time{"shared/key": now})
+	team := seedVault(t, map[string]time.Time{"shared/key": now, "team/only": now})
+	stack := openStack(t, user, team)
+
+	v, path, err := stack.Resolve("shared/key")
+	if err != nil {
+		t.Fatalf("Resolve error = %v", err)
+	}
+	if v.Name != "user" || path != filepath.Join(user, "shared", "key") {
+		t.Fatalf("Resolve = %q %q, want user vault", v.Name, path)
+	}
+
+	v, path, err = stack.Resolve("team/only")
+	if err != nil {
+		t.Fatalf("Resolve error = %v", err)
+	}
+	if v.Name != "team" || !v.ReadOnly || path != filepath.Join(team, "team", "only") {
+		t.Fatalf("Resolve = %q %q, want team vault", v.Name, path)
+	}
+
+	if _, _, err := stack.Resolve("missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("Resolve missing error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStackLookupTagsVault(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	user := seedVault(t, nil)
+	team := seedVault(t, map[string]time.Time{"team/only": now})
+	stack := openStack(t, user, team)
+
+	_, meta, err := stack.Lookup(context.Background(), "team/only")
+	if err != nil {
+		t.Fatalf("Lookup error = %v", err)
+	}
+	if meta.Vault != "team" {
+		t.Fatalf("Vault = %q, want team", meta.Vault)
+	}
+
+	if _, _, err := stack.Lookup(context.Background(), "missing"); !errors.Is(err, storage.ErrMetadataNotFound) {
+		t.Fatalf("Lookup missing error = %v, want ErrMetadataNotFound", err)
+	}
+}
+
+func TestStackListMergesAndHidesShadowed(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	user := seedVault(t, map[string]time.Time{"shared/key": now.Add(-time.Hour)})
+	team := seedVault(t, map[string]time.Time{"shared/key": now, "team/only": now.Add(time.Hour)})
+	stack := openStack(t, user, team)
+
+	entries, err := stack.List(context.Background())
+	if err != nil {
+		t.Fatalf("List error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(entries))
+	}
+	if entries[0].Key != "team/only" || entries[0].Vault != "team" {
+		t.Fatalf("entries[0] = %+v, want team/only from team", entries[0])
+	}
+	if entries[1].Key != "shared/key" || entries[1].Vault != "user" {
+		t.Fatalf("entries[1] = %+v, want shared/key from user", entries[1])
+	}
+
+	shadows, err := stack.Shadowed(context.Background())
+	if err != nil {
+		t.Fatalf("Shadowed error = %v", err)
+	}
+	if len(shadows) != 1 {
+		t.Fatalf("len(shadows) = %d, want 1", len(shadows))
+	}
+	if got := shadows[0]; got.Key != "shared/key" || got.Winner != "user" || len(got.Hidden) != 1 || got.Hidden[0] != "team" {
+		t.Fatalf("shadow = %+v, want shared/key won by user over team", got)
+	}
+}
+
+func TestOpenMigratesUserVaultOutOfScope(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meta.db")
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open error = %v", err)
+	}
+	if _, err := old.Exec(`CREATE TABLE snippets (key TEXT PRIMARY KEY, type TEXT NOT NULL DEFAULT 'text', created DATETIME NOT NULL, modified DATETIME NOT NULL, description TEXT, tags TEXT)`); err != nil {
+		t.Fatalf("create old schema error = %v", err)
+	}
+	_ = old.Close()
+
+	// Inside a project the user vault is out of scope, but it is still
+	// the user's own and must keep up with the schema.
+	stack, err := Open([]config.Vault{
+		{Name: config.UserVault, BaseDir: dir, MetaDB: path, ReadOnly: true},
+	})
+	if err != nil {
+		t.Fatalf("Open error = %v", err)
+	}
+	defer stack.Close()
+
+	if !stack[0].ReadOnly {
+		t.Fatal("user vault lost its read-only scope")
+	}
+	if _, err := stack.List(context.Background()); err != nil {
+		t.Fatalf("List error = %v", err)
+	}
+}